@@ -1,30 +1,144 @@
 // Package aiclient provides an interface for AI-based summarization clients.
 // It defines the GenAIClient interface, which is used to send text to an AI model
-// and receive a summarized response.
+// and receive a summarized response, the BatchClient interface, which submits many
+// requests as one asynchronous batch job, and the Embedder interface, which computes
+// vector embeddings of texts.
 package aiclient
 
-// Client defines an interface for summarization clients.
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GenAIClient defines an interface for summarization clients.
 type GenAIClient interface {
-	// Summarize summarizes the content of the given page URLs.
+	// Send sends a generation request to the model.
 	// Parameters:
-	//   - pageUrl: A slice of strings representing the URLs to summarize.
+	//   - ctx: The context of the request. Cancelling it aborts the generation.
+	//   - req: The system instruction, conversation and generation options.
 	// Returns:
-	//   - string: The summarized content.
-	//   - error: An error if the summarization fails.
-	Send(text string) (string, error)
+	//   - *Response: The generated content and the backend that produced it.
+	//   - error: An error if the generation fails.
+	Send(ctx context.Context, req Request) (*Response, error)
+}
+
+// Describer is implemented by clients that can tell which backend their requests go to.
+// Decorators such as RetryClient describe the clients they wrap.
+type Describer interface {
+	// Describe returns the kind, the resolved model and the endpoint of the backend,
+	// e.g. "openai:gpt-4o-mini@https://api.openai.com/v1", or of every backend of a
+	// FallbackClient, separated by commas.
+	Describe() string
+}
+
+// Describe describes the backend a client sends its requests to, for example to key
+// cached responses by the backend that actually produced them.
+// Parameters:
+//   - client: The client to describe.
+//
+// Returns:
+//   - string: The description, or an empty string if the client is not a Describer.
+func Describe(client GenAIClient) string {
+	if d, ok := client.(Describer); ok {
+		return d.Describe()
+	}
+	return ""
+}
+
+// describeBackend formats the description of a backend for Describe.
+func describeBackend(kind, model, endpoint string) string {
+	return backendName(kind, model) + "@" + strings.TrimRight(endpoint, "/")
 }
 
 // NewGenAIClient creates a new AI client of the specified type.
+// The model and the Gemini backend are taken from cfg. "gemini" uses Vertex AI
+// when cfg.GCPProjectID is set and the Gemini API with GEMINI_API_KEY otherwise.
+// The HTTP based kinds are configured through environment variables:
+//   - "openai": OPENAI_BASE_URL, OPENAI_API_KEY and OPENAI_MODEL
+//   - "anthropic": ANTHROPIC_BASE_URL, ANTHROPIC_API_KEY and ANTHROPIC_MODEL
+//   - "ollama": OLLAMA_HOST, OLLAMA_MODEL, OLLAMA_KEEP_ALIVE and OLLAMA_NUM_CTX
+//   - "extractive": EXTRACTIVE_SENTENCES, the number of sentences extracted per item
+//
 // Parameters:
-//   - kind: Type of AI client to create. Currently only "gemini" is supported.
+//   - ctx: The context used to set up the client.
+//   - kind: Type of AI client to create. One of "gemini", "openai", "anthropic", "ollama" or "extractive".
+//   - cfg: The client settings. cfg.Model takes precedence over the *_MODEL variables.
 //
 // Returns:
-//   - GenAIClient: A new instance of the AI client, or nil if the type is not supported.
-func NewGenAIClient(kind string) GenAIClient {
+//   - GenAIClient: A new instance of the AI client.
+//   - error: An error if the type is not supported or the client cannot be set up.
+func NewGenAIClient(ctx context.Context, kind string, cfg Config) (GenAIClient, error) {
 	switch kind {
 	case "gemini":
-		return NewGeminiClient("gemini-2.5-flash-lite")
+		clientConfig, err := cfg.geminiClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		return NewGeminiClient(ctx, modelOrDefault(cfg.Model, defaultGeminiModel), clientConfig)
+	case "openai":
+		return NewOpenAIClient(
+			getEnv("OPENAI_BASE_URL", defaultOpenAIBaseURL),
+			os.Getenv("OPENAI_API_KEY"),
+			modelOrDefault(cfg.Model, getEnv("OPENAI_MODEL", defaultOpenAIModel)),
+		), nil
+	case "anthropic":
+		return NewAnthropicClient(
+			getEnv("ANTHROPIC_BASE_URL", defaultAnthropicBaseURL),
+			os.Getenv("ANTHROPIC_API_KEY"),
+			modelOrDefault(cfg.Model, getEnv("ANTHROPIC_MODEL", defaultAnthropicModel)),
+		), nil
+	case "ollama":
+		numCtx := 0
+		if v := os.Getenv("OLLAMA_NUM_CTX"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OLLAMA_NUM_CTX %q: %w", v, err)
+			}
+			numCtx = n
+		}
+		client := NewOllamaClient(
+			getEnv("OLLAMA_HOST", defaultOllamaHost),
+			modelOrDefault(cfg.Model, getEnv("OLLAMA_MODEL", defaultOllamaModel)),
+			OllamaOptions{
+				KeepAlive: os.Getenv("OLLAMA_KEEP_ALIVE"),
+				NumCtx:    numCtx,
+			},
+		)
+		if err := client.CheckModel(ctx); err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "extractive":
+		sentences := 0
+		if v := os.Getenv("EXTRACTIVE_SENTENCES"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid EXTRACTIVE_SENTENCES %q: %w", v, err)
+			}
+			sentences = n
+		}
+		return NewExtractiveClient(sentences), nil
 	default:
-		return nil
+		return nil, fmt.Errorf("unsupported API type: %s", kind)
+	}
+}
+
+// getEnv returns the value of the environment variable named by key,
+// or fallback if the variable is unset or empty.
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// modelOrDefault returns model, or fallback if model is empty.
+func modelOrDefault(model, fallback string) string {
+	if model != "" {
+		return model
 	}
+	return fallback
 }
@@ -0,0 +1,106 @@
+package aiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+func TestNewGenAIClient(t *testing.T) {
+	t.Setenv("OPENAI_BASE_URL", "http://localhost:8080/v1")
+	t.Setenv("OPENAI_MODEL", "local-model")
+
+	client, err := NewGenAIClient(context.Background(), "openai", Config{})
+	assert.NoError(t, err)
+	openAI, ok := client.(*OpenAIClient)
+	assert.True(t, ok, "expected *OpenAIClient")
+	assert.Equal(t, "http://localhost:8080/v1", openAI.baseURL)
+	assert.Equal(t, "local-model", openAI.model)
+
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	client, err = NewGenAIClient(context.Background(), "gemini", Config{})
+	assert.NoError(t, err)
+	assert.IsType(t, &GeminiClient{}, client)
+
+	client, err = NewGenAIClient(context.Background(), "anthropic", Config{Model: "claude-sonnet-4-5"})
+	assert.NoError(t, err)
+	assert.IsType(t, &AnthropicClient{}, client)
+	assert.Equal(t, "claude-sonnet-4-5", client.(*AnthropicClient).model, "cfg.Model should override the default model")
+
+	t.Setenv("EXTRACTIVE_SENTENCES", "5")
+	client, err = NewGenAIClient(context.Background(), "extractive", Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, client.(*ExtractiveClient).sentences)
+
+	_, err = NewGenAIClient(context.Background(), "unknown", Config{})
+	assert.Error(t, err)
+}
+
+func TestNewGenAIClient_Ollama(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	t.Setenv("OLLAMA_HOST", ts.URL)
+	t.Setenv("OLLAMA_NUM_CTX", "4096")
+	_, err := NewGenAIClient(context.Background(), "ollama", Config{})
+	assert.Error(t, err, "expected an error for a model that has not been pulled")
+
+	t.Setenv("OLLAMA_NUM_CTX", "large")
+	_, err = NewGenAIClient(context.Background(), "ollama", Config{})
+	assert.Error(t, err, "expected an error for an invalid OLLAMA_NUM_CTX")
+}
+
+func TestConfig_geminiClientConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          Config
+		wantBackend  genai.Backend
+		wantLocation string
+		wantErr      bool
+	}{
+		{
+			name:        "gemini api",
+			cfg:         Config{},
+			wantBackend: genai.BackendGeminiAPI,
+		},
+		{
+			name:         "vertex ai",
+			cfg:          Config{GCPProjectID: "project", GCPLocation: "europe-west4"},
+			wantBackend:  genai.BackendVertexAI,
+			wantLocation: "europe-west4",
+		},
+		{
+			name:         "vertex ai with default location",
+			cfg:          Config{GCPProjectID: "project"},
+			wantBackend:  genai.BackendVertexAI,
+			wantLocation: defaultGCPLocation,
+		},
+		{
+			name:    "location without project",
+			cfg:     Config{GCPLocation: "us-central1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientConfig, err := tt.cfg.geminiClientConfig()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBackend, clientConfig.Backend)
+			if tt.wantBackend == genai.BackendVertexAI {
+				assert.Equal(t, tt.cfg.GCPProjectID, clientConfig.Project)
+				assert.Equal(t, tt.wantLocation, clientConfig.Location)
+			}
+		})
+	}
+}
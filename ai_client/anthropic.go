@@ -0,0 +1,289 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// defaultAnthropicBaseURL is the endpoint used when no base URL is configured.
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+
+	// defaultAnthropicModel is the model used when no model is configured.
+	defaultAnthropicModel = "claude-haiku-4-5"
+
+	// anthropicVersion is the API version sent in the anthropic-version header.
+	anthropicVersion = "2023-06-01"
+
+	// anthropicResponseTool is the name of the tool used to enforce a response schema.
+	anthropicResponseTool = "respond"
+
+	// defaultAnthropicMaxTokens is the output token limit used when MaxOutputTokens is not set.
+	// The Messages API requires max_tokens to be set explicitly.
+	defaultAnthropicMaxTokens = 4096
+)
+
+// AnthropicClient is a client for the Anthropic Messages API.
+// The system prompt is sent in the dedicated system field instead of being
+// prepended to the user text.
+type AnthropicClient struct {
+	// baseURL is the API root without the version prefix, e.g. "https://api.anthropic.com".
+	baseURL string
+
+	// apiKey is sent in the x-api-key header.
+	apiKey string
+
+	// model specifies the model to use for content generation.
+	model string
+
+	// httpClient is the HTTP client used to call the API.
+	httpClient *http.Client
+}
+
+// NewAnthropicClient creates a new instance of AnthropicClient.
+// Parameters:
+//   - baseURL: The API root without the version prefix, e.g. "https://api.anthropic.com".
+//   - apiKey: The API key sent in the x-api-key header.
+//   - model: A string representing the model to use.
+//
+// Returns:
+//   - *AnthropicClient: A pointer to the newly created AnthropicClient instance.
+func NewAnthropicClient(baseURL, apiKey, model string) *AnthropicClient {
+	return &AnthropicClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+	}
+}
+
+// Describe returns the model and the base URL of the client.
+func (a *AnthropicClient) Describe() string {
+	return describeBackend("anthropic", a.model, a.baseURL)
+}
+
+// anthropicMessage is a single message of a Messages API request.
+// Content is a string, or a list of anthropicRequestBlock for messages with images.
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// anthropicRequestBlock is a text or image content block of a Messages API request.
+type anthropicRequestBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+// anthropicImageSource is the base64 encoded image of an image block.
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+}
+
+// anthropicRequest is the request body of the Messages API.
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	MaxTokens     int32              `json:"max_tokens"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	Temperature   *float32           `json:"temperature,omitempty"`
+	TopP          *float32           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice    *anthropicChoice   `json:"tool_choice,omitempty"`
+}
+
+// anthropicTool is a tool definition of a Messages API request.
+type anthropicTool struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	InputSchema *Schema `json:"input_schema"`
+}
+
+// anthropicChoice forces the model to call a specific tool.
+type anthropicChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// anthropicContentBlock is a single content block of a Messages API response.
+type anthropicContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// anthropicResponse is the response body of the Messages API.
+type anthropicResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+	Error      *anthropicError         `json:"error,omitempty"`
+}
+
+// anthropicUsage is the token usage of a Messages API response.
+// InputTokens excludes the tokens read from or written to the prompt cache.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// usage converts the token usage, counting cached input tokens as prompt tokens.
+func (u anthropicUsage) usage() Usage {
+	return Usage{
+		PromptTokens: u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		OutputTokens: u.OutputTokens,
+		CachedTokens: u.CacheReadInputTokens,
+	}
+}
+
+// anthropicError is the error object returned by the Messages API.
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Send sends a request to the Messages API.
+// The system instruction is sent in the system field. The Messages API has no
+// seed parameter, so GenerationOptions.Seed is ignored.
+// A response schema is enforced by forcing a call to a tool whose input schema is
+// the response schema; the tool input is returned as the response.
+// Parameters:
+//   - ctx: The context of the request. Cancelling it aborts the request.
+//   - req: The system instruction, conversation and generation options.
+//
+// Returns:
+//   - *Response: The concatenated text blocks of the response.
+//   - error: An error if the request fails, or a FinishError if the model refused or ran out of tokens.
+func (a *AnthropicClient) Send(ctx context.Context, req Request) (*Response, error) {
+	header := http.Header{}
+	header.Set("x-api-key", a.apiKey)
+	header.Set("anthropic-version", anthropicVersion)
+
+	maxTokens := req.Options.MaxOutputTokens
+	if maxTokens == 0 {
+		maxTokens = defaultAnthropicMaxTokens
+	}
+
+	messages := make([]anthropicMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, anthropicMessage{Role: string(m.Role), Content: anthropicContent(m)})
+	}
+
+	msgReq := anthropicRequest{
+		Model:         a.model,
+		MaxTokens:     maxTokens,
+		System:        req.SystemInstruction,
+		Messages:      messages,
+		Temperature:   req.Options.Temperature,
+		TopP:          req.Options.TopP,
+		StopSequences: req.Options.StopSequences,
+	}
+	wrapped := false
+	if req.ResponseSchema != nil {
+		var schema *Schema
+		schema, wrapped = req.ResponseSchema.objectRoot()
+		msgReq.Tools = []anthropicTool{{
+			Name:        anthropicResponseTool,
+			Description: "Return the response in the required structure.",
+			InputSchema: schema,
+		}}
+		msgReq.ToolChoice = &anthropicChoice{Type: "tool", Name: anthropicResponseTool}
+	}
+
+	res, err := postJSON(ctx, a.httpClient, a.baseURL+"/v1/messages", header, msgReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call messages API: %w", err)
+	}
+
+	var msgResp anthropicResponse
+	if err := json.Unmarshal(res.Body, &msgResp); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse messages response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		message := ""
+		if msgResp.Error != nil {
+			message = msgResp.Error.Type + ": " + msgResp.Error.Message
+		}
+		return nil, res.apiError("messages API", message)
+	}
+
+	backend := backendName("anthropic", a.model)
+	switch msgResp.StopReason {
+	case "max_tokens":
+		// A truncated tool input is incomplete JSON, so only text is offered for continuation.
+		var partial *Response
+		if req.ResponseSchema == nil {
+			partial = &Response{Text: anthropicText(msgResp.Content), Backend: backend, Usage: msgResp.Usage.usage()}
+		}
+		return nil, finishError("messages API", FinishReasonMaxTokens, fmt.Sprintf("after %d tokens", maxTokens), partial)
+	case "refusal":
+		return nil, finishError("messages API", FinishReasonSafety, msgResp.StopReason, &Response{Backend: backend, Usage: msgResp.Usage.usage()})
+	}
+
+	resp := &Response{Backend: backend, Usage: msgResp.Usage.usage(), FinishReason: FinishReasonStop}
+	if req.ResponseSchema != nil {
+		for _, block := range msgResp.Content {
+			if block.Type == "tool_use" && block.Name == anthropicResponseTool {
+				resp.Text = string(block.Input)
+				if wrapped {
+					if resp.Text, err = unwrapSchemaResult(resp.Text); err != nil {
+						return nil, err
+					}
+				}
+				return resp, nil
+			}
+		}
+		return nil, fmt.Errorf("messages API returned no structured response (stop_reason: %s)", msgResp.StopReason)
+	}
+
+	resp.Text = anthropicText(msgResp.Content)
+	if resp.Text == "" {
+		return nil, fmt.Errorf("messages API returned no text content (stop_reason: %s): %w", msgResp.StopReason, ErrEmptyResponse)
+	}
+	return resp, nil
+}
+
+// anthropicText concatenates the text blocks of a response.
+func anthropicText(content []anthropicContentBlock) string {
+	var sb strings.Builder
+	for _, block := range content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	return sb.String()
+}
+
+// anthropicContent maps a message to the content of a Messages API message: the text alone,
+// or image blocks followed by a text block when images are attached.
+// Parameters:
+//   - m: The message to map.
+//
+// Returns:
+//   - any: The string or []anthropicRequestBlock content.
+func anthropicContent(m Message) any {
+	if len(m.Images) == 0 {
+		return m.Text
+	}
+	blocks := make([]anthropicRequestBlock, 0, len(m.Images)+1)
+	for _, image := range m.Images {
+		blocks = append(blocks, anthropicRequestBlock{
+			Type:   "image",
+			Source: &anthropicImageSource{Type: "base64", MediaType: image.MIMEType, Data: image.Data},
+		})
+	}
+	return append(blocks, anthropicRequestBlock{Type: "text", Text: m.Text})
+}
@@ -0,0 +1,175 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnthropicClient_Send(t *testing.T) {
+	var gotReq anthropicRequest
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+
+		_, err := w.Write([]byte(`{
+			"type": "message",
+			"role": "assistant",
+			"content": [
+				{"type": "text", "text": "[{\"heading\": \"h\","},
+				{"type": "text", "text": " \"summary\": \"s\"}]"}
+			],
+			"stop_reason": "end_turn",
+			"usage": {"input_tokens": 20, "cache_read_input_tokens": 100, "output_tokens": 15}
+		}`))
+		assert.NoError(t, err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	client := NewAnthropicClient(ts.URL+"/", "test-key", "test-model")
+	result, err := client.Send(context.Background(), NewUserRequest("system", "user text", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"heading": "h", "summary": "s"}]`, result.Text)
+	assert.Equal(t, Usage{PromptTokens: 120, OutputTokens: 15, CachedTokens: 100}, result.Usage)
+	assert.Equal(t, "test-model", gotReq.Model)
+	assert.Equal(t, int32(defaultAnthropicMaxTokens), gotReq.MaxTokens)
+	assert.Equal(t, "system", gotReq.System)
+	assert.Equal(t, []anthropicMessage{{Role: "user", Content: "user text"}}, gotReq.Messages)
+}
+
+func TestAnthropicClient_GenerationOptions(t *testing.T) {
+	var gotReq anthropicRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"stop_sequence"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	temperature := float32(0.2)
+	client := NewAnthropicClient(ts.URL, "key", "model")
+	_, err := client.Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{
+		Temperature:     &temperature,
+		MaxOutputTokens: 512,
+		StopSequences:   []string{"END"},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(512), gotReq.MaxTokens)
+	assert.Equal(t, &temperature, gotReq.Temperature)
+	assert.Nil(t, gotReq.TopP)
+	assert.Equal(t, []string{"END"}, gotReq.StopSequences)
+}
+
+func TestAnthropicClient_Send_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "api error",
+			status:  http.StatusUnauthorized,
+			body:    `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			wantErr: "authentication_error: invalid x-api-key",
+		},
+		{
+			name:    "max tokens",
+			status:  http.StatusOK,
+			body:    `{"content":[{"type":"text","text":"[{\"heading\":"}],"stop_reason":"max_tokens"}`,
+			wantErr: "truncated",
+		},
+		{
+			name:    "refusal",
+			status:  http.StatusOK,
+			body:    `{"content":[],"stop_reason":"refusal"}`,
+			wantErr: "refused",
+		},
+		{
+			name:    "no text content",
+			status:  http.StatusOK,
+			body:    `{"content":[{"type":"tool_use"}],"stop_reason":"tool_use"}`,
+			wantErr: "no text content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, err := w.Write([]byte(tt.body))
+				assert.NoError(t, err)
+			}))
+			defer ts.Close()
+
+			_, err := NewAnthropicClient(ts.URL, "key", "model").Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestAnthropicClient_Send_RefusalUsage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"content":[],"stop_reason":"refusal","usage":{"input_tokens":10,"output_tokens":5}}`))
+	}))
+	defer ts.Close()
+
+	_, err := NewAnthropicClient(ts.URL, "key", "model").Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+	assert.ErrorIs(t, err, ErrSafetyBlocked)
+	var finishErr *FinishError
+	if assert.ErrorAs(t, err, &finishErr) && assert.NotNil(t, finishErr.Partial, "the usage of a refused request should be reported") {
+		assert.Equal(t, Usage{PromptTokens: 10, OutputTokens: 5}, finishErr.Partial.Usage)
+	}
+}
+
+func TestAnthropicClient_Send_ResponseSchema(t *testing.T) {
+	var gotReq anthropicRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{
+			"content": [{"type":"tool_use","id":"toolu_1","name":"respond","input":{"result":[{"heading":"h","summary":"s"}]}}],
+			"stop_reason": "tool_use"
+		}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	req := NewUserRequest("system", "hello", GenerationOptions{})
+	req.ResponseSchema = testSummarySchema
+	result, err := NewAnthropicClient(ts.URL, "key", "model").Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"heading":"h","summary":"s"}]`, result.Text)
+
+	assert.Len(t, gotReq.Tools, 1)
+	assert.Equal(t, anthropicResponseTool, gotReq.Tools[0].Name)
+	assert.Equal(t, SchemaTypeObject, gotReq.Tools[0].InputSchema.Type)
+	assert.Equal(t, &anthropicChoice{Type: "tool", Name: anthropicResponseTool}, gotReq.ToolChoice)
+}
+
+func TestAnthropicClient_Send_Images(t *testing.T) {
+	var gotReq map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	req := Request{Messages: []Message{{Role: RoleUser, Text: "describe", Images: []Image{{MIMEType: "image/jpeg", Data: []byte("jpg")}}}}}
+	_, err := NewAnthropicClient(ts.URL, "key", "model").Send(context.Background(), req)
+	assert.NoError(t, err)
+
+	content := gotReq["messages"].([]any)[0].(map[string]any)["content"]
+	assert.Equal(t, []any{
+		map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/jpeg", "data": "anBn"}},
+		map[string]any{"type": "text", "text": "describe"},
+	}, content)
+}
@@ -0,0 +1,171 @@
+package aiclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// BatchState is the state of a batch job, normalized across backends.
+type BatchState string
+
+const (
+	// BatchPending means the job is queued or being validated.
+	BatchPending BatchState = "pending"
+
+	// BatchRunning means the requests of the job are being processed.
+	BatchRunning BatchState = "running"
+
+	// BatchSucceeded means the job finished and its results are available.
+	// Individual requests may still have failed.
+	BatchSucceeded BatchState = "succeeded"
+
+	// BatchFailed means the job failed as a whole.
+	BatchFailed BatchState = "failed"
+
+	// BatchCancelled means the job was cancelled.
+	BatchCancelled BatchState = "cancelled"
+
+	// BatchExpired means the job did not finish within the backend's completion window.
+	BatchExpired BatchState = "expired"
+)
+
+// Done reports whether the job has stopped, successfully or not.
+func (s BatchState) Done() bool {
+	switch s {
+	case BatchSucceeded, BatchFailed, BatchCancelled, BatchExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// BatchRequest is a request submitted as part of a batch job.
+type BatchRequest struct {
+	// ID identifies the request within the job. It must be unique within the job.
+	ID string
+
+	// Request is the generation request.
+	Request Request
+}
+
+// BatchResult is the outcome of a single request of a batch job.
+type BatchResult struct {
+	// ID is the ID of the BatchRequest.
+	ID string
+
+	// Response is the generated content, or nil if the request failed.
+	Response *Response
+
+	// Err is the error of the request, or nil if it succeeded.
+	Err error
+}
+
+// BatchJob is the status of a batch job.
+type BatchJob struct {
+	// ID identifies the job at the backend. Persist it to resume waiting for the job.
+	ID string
+
+	// State is the state of the job.
+	State BatchState
+
+	// Message describes why the job failed. It may be empty.
+	Message string
+
+	// Results holds the outcome of each request once the job succeeded, in no particular order.
+	Results []BatchResult
+}
+
+// BatchClient defines an interface for backends that process many requests
+// asynchronously as one batch job, usually at a discounted price.
+type BatchClient interface {
+	// SubmitBatch creates a batch job of the requests.
+	// Parameters:
+	//   - ctx: The context of the submission.
+	//   - requests: The requests with their unique IDs.
+	// Returns:
+	//   - string: The ID of the created job.
+	//   - error: An error if the job cannot be created.
+	SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error)
+
+	// GetBatch retrieves the status of a batch job, with its results once it succeeded.
+	// Parameters:
+	//   - ctx: The context of the request.
+	//   - id: The ID returned by SubmitBatch.
+	// Returns:
+	//   - *BatchJob: The status of the job.
+	//   - error: An error if the status cannot be retrieved.
+	GetBatch(ctx context.Context, id string) (*BatchJob, error)
+}
+
+// NewBatchClient creates a new batch client of the specified type.
+// "gemini" uses the Gemini API batch mode with GEMINI_API_KEY; Vertex AI batch
+// prediction reads from Cloud Storage and is not supported. "openai" uses the
+// OpenAI Batch API, configured like NewGenAIClient.
+// Parameters:
+//   - ctx: The context used to set up the client.
+//   - kind: Type of batch client to create. One of "gemini" or "openai".
+//   - cfg: The client settings. cfg.Model takes precedence over the default model.
+//
+// Returns:
+//   - BatchClient: A new instance of the batch client.
+//   - error: An error if the type does not support batch jobs.
+func NewBatchClient(_ context.Context, kind string, cfg Config) (BatchClient, error) {
+	switch kind {
+	case "gemini":
+		if cfg.vertexAI() {
+			return nil, fmt.Errorf("batch mode is not supported on Vertex AI")
+		}
+		baseURL := cfg.GeminiBaseURL
+		if baseURL == "" {
+			baseURL = defaultGeminiBaseURL
+		}
+		return NewGeminiBatchClient(baseURL, os.Getenv("GEMINI_API_KEY"), modelOrDefault(cfg.Model, defaultGeminiModel)), nil
+	case "openai":
+		return NewOpenAIBatchClient(
+			getEnv("OPENAI_BASE_URL", defaultOpenAIBaseURL),
+			os.Getenv("OPENAI_API_KEY"),
+			modelOrDefault(cfg.Model, getEnv("OPENAI_MODEL", defaultOpenAIModel)),
+		), nil
+	default:
+		return nil, fmt.Errorf("batch mode is not supported by API type: %s", kind)
+	}
+}
+
+// WaitBatch polls a batch job until it is done.
+// Parameters:
+//   - ctx: The context of the wait. Cancelling it stops polling; the job keeps running.
+//   - client: The batch client that submitted the job.
+//   - id: The ID of the job.
+//   - interval: The time between polls.
+//   - onPoll: Called with the status of each poll, e.g. to log progress. May be nil.
+//
+// Returns:
+//   - *BatchJob: The final status of the job with its results.
+//   - error: An error if polling fails, ctx is cancelled, or the job did not succeed.
+func WaitBatch(ctx context.Context, client BatchClient, id string, interval time.Duration, onPoll func(*BatchJob)) (*BatchJob, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := client.GetBatch(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get batch job %s: %w", id, err)
+		}
+		if onPoll != nil {
+			onPoll(job)
+		}
+		if job.State.Done() {
+			if job.State != BatchSucceeded {
+				return job, fmt.Errorf("batch job %s %s: %s", id, job.State, job.Message)
+			}
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package aiclient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeOpenAIBatchServer is an in-memory implementation of the OpenAI files and batches endpoints.
+// Every batch completes on the second poll with a summary of each input line, except for
+// lines whose custom ID is "fail", which are reported in the error file.
+func fakeOpenAIBatchServer(t *testing.T, input *[]openAIBatchLine) *httptest.Server {
+	t.Helper()
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/files", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "batch", r.FormValue("purpose"))
+		file, _, err := r.FormFile("file")
+		if !assert.NoError(t, err) {
+			return
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var line openAIBatchLine
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+			*input = append(*input, line)
+		}
+		_, _ = w.Write([]byte(`{"id":"file-in","object":"file"}`))
+	})
+	mux.HandleFunc("POST /v1/batches", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]string{
+			"input_file_id":     "file-in",
+			"endpoint":          "/v1/chat/completions",
+			"completion_window": "24h",
+		}, body)
+		_, _ = w.Write([]byte(`{"id":"batch-1","status":"validating"}`))
+	})
+	mux.HandleFunc("GET /v1/batches/batch-1", func(w http.ResponseWriter, _ *http.Request) {
+		polls++
+		if polls < 2 {
+			_, _ = w.Write([]byte(`{"id":"batch-1","status":"in_progress"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"batch-1","status":"completed","output_file_id":"file-out","error_file_id":"file-err"}`))
+	})
+	mux.HandleFunc("GET /v1/files/file-out/content", func(w http.ResponseWriter, _ *http.Request) {
+		encoder := json.NewEncoder(w)
+		for _, line := range *input {
+			if line.CustomID == "fail" {
+				continue
+			}
+			content := `summary of ` + line.CustomID
+			if line.Body.ResponseFormat != nil {
+				content = `{"result":[{"heading":"h","summary":"s"}]}`
+			}
+			body, _ := json.Marshal(map[string]any{
+				"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": content}, "finish_reason": "stop"}},
+				"usage":   map[string]any{"prompt_tokens": 10, "completion_tokens": 2},
+			})
+			assert.NoError(t, encoder.Encode(map[string]any{
+				"custom_id": line.CustomID,
+				"response":  map[string]any{"status_code": 200, "body": json.RawMessage(body)},
+			}))
+		}
+	})
+	mux.HandleFunc("GET /v1/files/file-err/content", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"custom_id":"fail","response":{"status_code":400,"body":{"error":{"message":"bad request"}}}}` + "\n"))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestOpenAIBatchClient(t *testing.T) {
+	var input []openAIBatchLine
+	ts := fakeOpenAIBatchServer(t, &input)
+	client := NewOpenAIBatchClient(ts.URL+"/v1", "test-key", "test-model")
+	ctx := context.Background()
+
+	schema := &Schema{Type: SchemaTypeArray, Items: &Schema{Type: SchemaTypeString}}
+	id, err := client.SubmitBatch(ctx, []BatchRequest{
+		{ID: "a", Request: NewUserRequest("system", "first", GenerationOptions{})},
+		{ID: "b", Request: Request{Messages: []Message{{Role: RoleUser, Text: "second"}}, ResponseSchema: schema}},
+		{ID: "fail", Request: NewUserRequest("", "third", GenerationOptions{})},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "batch-1", id)
+	if !assert.Len(t, input, 3) {
+		return
+	}
+	assert.Equal(t, "a", input[0].CustomID)
+	assert.Equal(t, "POST", input[0].Method)
+	assert.Equal(t, "/v1/chat/completions", input[0].URL)
+	assert.Equal(t, "test-model", input[0].Body.Model)
+	assert.Len(t, input[0].Body.Messages, 2)
+	// The wrapped schema is recorded in the custom ID so the result can be unwrapped later.
+	assert.Equal(t, "w:b", input[1].CustomID)
+
+	job, err := client.GetBatch(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, BatchRunning, job.State)
+	assert.Empty(t, job.Results)
+
+	job, err = client.GetBatch(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, BatchSucceeded, job.State)
+	if !assert.Len(t, job.Results, 3) {
+		return
+	}
+
+	results := map[string]BatchResult{}
+	for _, result := range job.Results {
+		results[result.ID] = result
+	}
+	assert.NoError(t, results["a"].Err)
+	assert.Equal(t, "summary of a", results["a"].Response.Text)
+	assert.Equal(t, "openai-batch:test-model", results["a"].Response.Backend)
+	assert.Equal(t, Usage{PromptTokens: 10, OutputTokens: 2}, results["a"].Response.Usage)
+	assert.NoError(t, results["b"].Err)
+	assert.JSONEq(t, `[{"heading":"h","summary":"s"}]`, results["b"].Response.Text)
+
+	var apiErr *APIError
+	assert.ErrorAs(t, results["fail"].Err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "bad request", apiErr.Message)
+}
+
+func TestGeminiBatchClient(t *testing.T) {
+	var created map[string]any
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1beta/models/test-model:batchGenerateContent", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+		_, _ = w.Write([]byte(`{"name":"batches/123","metadata":{"state":"BATCH_STATE_PENDING"}}`))
+	})
+	mux.HandleFunc("GET /v1beta/batches/123", func(w http.ResponseWriter, _ *http.Request) {
+		polls++
+		if polls < 2 {
+			_, _ = w.Write([]byte(`{"name":"batches/123","metadata":{"state":"BATCH_STATE_RUNNING"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"batches/123","done":true,"metadata":{"state":"BATCH_STATE_SUCCEEDED","output":{"inlinedResponses":{"inlinedResponses":[
+			{"response":{"candidates":[{"content":{"parts":[{"text":"summary of b"}],"role":"model"},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":2}},"metadata":{"key":"b"}},
+			{"response":{"candidates":[{"content":{"parts":[{"text":"summary of a"}],"role":"model"},"finishReason":"STOP"}]},"metadata":{"key":"a"}},
+			{"response":{"candidates":[{"finishReason":"SAFETY"}]},"metadata":{"key":"blocked"}},
+			{"error":{"code":400,"message":"bad request"},"metadata":{"key":"fail"}}
+		]}}}}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	client := NewGeminiBatchClient(ts.URL, "test-key", "test-model")
+	ctx := context.Background()
+	temperature := float32(0.5)
+	id, err := client.SubmitBatch(ctx, []BatchRequest{
+		{ID: "a", Request: NewUserRequest("system", "first", GenerationOptions{Temperature: &temperature})},
+		{ID: "b", Request: NewUserRequest("", "second", GenerationOptions{})},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "batches/123", id)
+
+	requests := created["batch"].(map[string]any)["inputConfig"].(map[string]any)["requests"].(map[string]any)["requests"].([]any)
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+	first := requests[0].(map[string]any)
+	assert.Equal(t, map[string]any{"key": "a"}, first["metadata"])
+	request := first["request"].(map[string]any)
+	// The system instruction must be part of each inlined request.
+	assert.Equal(t, "system", request["systemInstruction"].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+	assert.Equal(t, "first", request["contents"].([]any)[0].(map[string]any)["parts"].([]any)[0].(map[string]any)["text"])
+	assert.Equal(t, 0.5, request["generationConfig"].(map[string]any)["temperature"])
+
+	job, err := client.GetBatch(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, BatchRunning, job.State)
+
+	job, err = client.GetBatch(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, BatchSucceeded, job.State)
+	if !assert.Len(t, job.Results, 4) {
+		return
+	}
+	assert.Equal(t, "b", job.Results[0].ID)
+	assert.Equal(t, "summary of b", job.Results[0].Response.Text)
+	assert.Equal(t, "gemini-batch:test-model", job.Results[0].Response.Backend)
+	assert.Equal(t, Usage{PromptTokens: 10, OutputTokens: 2}, job.Results[0].Response.Usage)
+	assert.Equal(t, "summary of a", job.Results[1].Response.Text)
+
+	var finishErr *FinishError
+	assert.ErrorAs(t, job.Results[2].Err, &finishErr)
+	assert.Equal(t, FinishReasonSafety, finishErr.Reason)
+	var apiErr *APIError
+	assert.ErrorAs(t, job.Results[3].Err, &apiErr)
+	assert.Equal(t, "bad request", apiErr.Message)
+}
+
+func TestGeminiBatchClient_APIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"code":429,"message":"quota exceeded"}}`))
+	}))
+	defer ts.Close()
+
+	_, err := NewGeminiBatchClient(ts.URL, "key", "model").SubmitBatch(context.Background(), []BatchRequest{
+		{ID: "a", Request: NewUserRequest("", "hello", GenerationOptions{})},
+	})
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, "quota exceeded", apiErr.Message)
+}
+
+// fakeBatchClient reports the states in order, one per GetBatch call.
+type fakeBatchClient struct {
+	states []BatchState
+	polls  int
+}
+
+func (f *fakeBatchClient) SubmitBatch(context.Context, []BatchRequest) (string, error) {
+	return "job", nil
+}
+
+func (f *fakeBatchClient) GetBatch(_ context.Context, id string) (*BatchJob, error) {
+	state := f.states[min(f.polls, len(f.states)-1)]
+	f.polls++
+	return &BatchJob{ID: id, State: state, Message: "reason"}, nil
+}
+
+func TestWaitBatch(t *testing.T) {
+	client := &fakeBatchClient{states: []BatchState{BatchPending, BatchRunning, BatchSucceeded}}
+	var polled []BatchState
+	job, err := WaitBatch(context.Background(), client, "job", time.Millisecond, func(job *BatchJob) {
+		polled = append(polled, job.State)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, BatchSucceeded, job.State)
+	assert.Equal(t, []BatchState{BatchPending, BatchRunning, BatchSucceeded}, polled)
+
+	_, err = WaitBatch(context.Background(), &fakeBatchClient{states: []BatchState{BatchExpired}}, "job", time.Millisecond, nil)
+	assert.ErrorContains(t, err, "batch job job expired: reason")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = WaitBatch(ctx, &fakeBatchClient{states: []BatchState{BatchRunning}}, "job", time.Hour, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
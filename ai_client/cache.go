@@ -0,0 +1,189 @@
+package aiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheEntry is the content of a cache file.
+type cacheEntry struct {
+	// Created is the time the response was stored.
+	Created time.Time `json:"created"`
+
+	// Response is the cached response.
+	Response Response `json:"response"`
+}
+
+// CachingClient is a GenAIClient that stores responses in a directory and serves
+// repeated requests from it until they expire. Requests are keyed by a hash of the
+// namespace and the whole request, so changing the prompt, options, schema or
+// model results in a new request. Responses served from the cache report no usage.
+type CachingClient struct {
+	// next is the client whose responses are cached.
+	next GenAIClient
+
+	// dir is the directory holding one file per cached response.
+	dir string
+
+	// namespace identifies the backend, model and endpoint, which are not part of the request.
+	namespace string
+
+	// ttl is how long a cached response is served.
+	ttl time.Duration
+
+	// now returns the current time. It is replaced in tests.
+	now func() time.Time
+}
+
+// NewCachingClient creates a new instance of CachingClient.
+// Parameters:
+//   - next: The client whose responses are cached.
+//   - dir: The cache directory. It is created on the first write.
+//   - namespace: Identifies the backend, model and endpoint, e.g. Describe of next.
+//   - ttl: How long a cached response is served.
+//
+// Returns:
+//   - *CachingClient: A pointer to the newly created CachingClient instance.
+func NewCachingClient(next GenAIClient, dir, namespace string, ttl time.Duration) *CachingClient {
+	return &CachingClient{
+		next:      next,
+		dir:       dir,
+		namespace: namespace,
+		ttl:       ttl,
+		now:       time.Now,
+	}
+}
+
+// Send returns the cached response of the request if there is an unexpired one,
+// and otherwise sends the request to the wrapped client and caches the response.
+// Failing to read or write the cache does not fail the request.
+// Parameters:
+//   - ctx: The context of the request.
+//   - req: The request to send.
+//
+// Returns:
+//   - *Response: The cached or generated response.
+//   - error: An error if the wrapped client fails.
+func (c *CachingClient) Send(ctx context.Context, req Request) (*Response, error) {
+	return c.send(req, func() (*Response, error) {
+		return c.next.Send(ctx, req)
+	}, nil)
+}
+
+// SendStream passes the cached response of the request to onChunk at once if there
+// is an unexpired one, and otherwise streams the request from the wrapped client and
+// caches the response.
+// Parameters:
+//   - ctx: The context of the request.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order.
+//
+// Returns:
+//   - *Response: The cached or generated response.
+//   - error: An error if the wrapped client or onChunk fails.
+func (c *CachingClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	return c.send(req, func() (*Response, error) {
+		return SendStream(ctx, c.next, req, onChunk)
+	}, onChunk)
+}
+
+// send serves the request from the cache, passing a cached response to onChunk if it
+// is not nil, or calls generate and caches its response.
+func (c *CachingClient) send(req Request, generate func() (*Response, error), onChunk func(string) error) (*Response, error) {
+	path, err := c.path(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp, ok := c.load(path); ok {
+		if onChunk != nil {
+			if err := onChunk(resp.Text); err != nil {
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+
+	resp, err := generate()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store(path, resp); err != nil {
+		log.Printf("failed to cache response: %v", err)
+	}
+	return resp, nil
+}
+
+// path returns the cache file of a request.
+func (c *CachingClient) path(req Request) (string, error) {
+	request, err := req.key()
+	if err != nil {
+		return "", fmt.Errorf("failed to compute cache key: %w", err)
+	}
+	key, err := json.Marshal(struct {
+		Namespace string
+		Request   json.RawMessage
+	}{c.namespace, request})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute cache key: %w", err)
+	}
+	sum := sha256.Sum256(key)
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// load reads the cache file at path. The reported bool is false if there is no
+// readable, unexpired entry.
+func (c *CachingClient) load(path string) (*Response, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("failed to read cached response: %v", err)
+		}
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Printf("ignoring corrupt cached response %s: %v", path, err)
+		return nil, false
+	}
+	if c.now().Sub(entry.Created) > c.ttl {
+		return nil, false
+	}
+	// A cached response costs nothing, so it reports no usage.
+	entry.Response.Usage = Usage{}
+	return &entry.Response, true
+}
+
+// store writes resp to the cache file at path. The file is written under a
+// temporary name and renamed, so concurrent readers never see a partial entry.
+func (c *CachingClient) store(path string, resp *Response) error {
+	data, err := json.Marshal(cacheEntry{Created: c.now(), Response: *resp})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	_, writeErr := tmp.Write(data)
+	if err := errors.Join(writeErr, tmp.Close()); err != nil {
+		return errors.Join(fmt.Errorf("failed to write cache file: %w", err), os.Remove(tmp.Name()))
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Join(fmt.Errorf("failed to move cache file into place: %w", err), os.Remove(tmp.Name()))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package aiclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingClient_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	next := &sequenceClient{text: "summary"}
+	client := NewCachingClient(next, dir, "gemini:model", time.Hour)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.now = func() time.Time { return now }
+
+	req := NewUserRequest("system", "feed", GenerationOptions{})
+	resp, err := client.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "summary", resp.Text)
+	assert.Equal(t, 1, next.calls)
+
+	resp, err = client.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, &Response{Text: "summary", Backend: "test:model"}, resp)
+	assert.Equal(t, 1, next.calls, "a repeated request should be served from the cache")
+
+	changed := NewUserRequest("system", "feed", GenerationOptions{MaxOutputTokens: 10})
+	_, err = client.Send(context.Background(), changed)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, next.calls, "changed options should miss the cache")
+
+	other := NewCachingClient(next, dir, "gemini:other-model", time.Hour)
+	_, err = other.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, next.calls, "another model should miss the cache")
+
+	withDocuments := req
+	withDocuments.Documents = []Document{{Title: "Item", Text: "Text."}}
+	_, err = client.Send(context.Background(), withDocuments)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, next.calls, "documents should not change the key of a request with messages")
+
+	now = now.Add(2 * time.Hour)
+	_, err = client.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, next.calls, "an expired entry should be refreshed")
+}
+
+func TestCachingClient_Send_DescribedModel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openAIChatRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": req.Model}, "finish_reason": "stop"}},
+		}))
+	}))
+	defer ts.Close()
+	t.Setenv("OPENAI_BASE_URL", ts.URL)
+	dir := t.TempDir()
+	req := NewUserRequest("system", "feed", GenerationOptions{})
+
+	// The model comes from the environment, so the flags that select the backend do not change.
+	send := func(model string) string {
+		t.Setenv("OPENAI_MODEL", model)
+		backend, err := NewGenAIClient(context.Background(), "openai", Config{})
+		assert.NoError(t, err)
+		assert.Equal(t, "openai:"+model+"@"+ts.URL, Describe(backend))
+		resp, err := NewCachingClient(backend, dir, Describe(backend), time.Hour).Send(context.Background(), req)
+		assert.NoError(t, err)
+		return resp.Text
+	}
+	assert.Equal(t, "model-a", send("model-a"))
+	assert.Equal(t, "model-b", send("model-b"), "another resolved model should miss the cache")
+	assert.Equal(t, "model-a", send("model-a"))
+}
+
+func TestDescribe(t *testing.T) {
+	primary := NewRetryClient(NewOpenAIClient("https://api.example.com/v1/", "", "gpt"), RetryPolicy{})
+	fallback, err := NewFallbackClient(
+		FallbackEntry{Name: "openai:gpt", Client: primary},
+		FallbackEntry{Name: "ollama", Client: NewOllamaClient("http://localhost:11434", "llama", OllamaOptions{NumCtx: 8192})},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "openai:gpt@https://api.example.com/v1,ollama:llama@http://localhost:11434?num_ctx=8192", Describe(fallback))
+	assert.Equal(t, "", Describe(&sequenceClient{}))
+}
+
+func TestCachingClient_path_Compatible(t *testing.T) {
+	client := NewCachingClient(&sequenceClient{}, "dir", "gemini:model", time.Hour)
+	req := NewUserRequest("system", "<feed>", GenerationOptions{})
+	req.Documents = []Document{{Title: "Item", Text: "Text."}}
+	path, err := client.path(req)
+	assert.NoError(t, err)
+
+	// The key of entries cached before requests carried documents.
+	req.Documents = nil
+	key, err := json.Marshal(struct {
+		Namespace string
+		Request   Request
+	}{"gemini:model", req})
+	assert.NoError(t, err)
+	sum := sha256.Sum256(key)
+	assert.Equal(t, filepath.Join("dir", hex.EncodeToString(sum[:])+".json"), path)
+}
+
+func TestCachingClient_Send_Errors(t *testing.T) {
+	dir := t.TempDir()
+	next := &sequenceClient{errs: []error{ErrEmptyResponse}, text: "summary"}
+	client := NewCachingClient(next, dir, "gemini:model", time.Hour)
+	req := NewUserRequest("", "feed", GenerationOptions{})
+
+	_, err := client.Send(context.Background(), req)
+	assert.ErrorIs(t, err, ErrEmptyResponse)
+
+	path, err := client.path(req)
+	assert.NoError(t, err)
+	assert.NoFileExists(t, path, "errors must not be cached")
+
+	assert.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	resp, err := client.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "summary", resp.Text, "a corrupt entry should be ignored and replaced")
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestCachingClient_Send_NoUsageOnHit(t *testing.T) {
+	next := &staticClient{resp: &Response{Text: "summary", Backend: "test:model", Usage: Usage{PromptTokens: 10, OutputTokens: 2}}}
+	client := NewCachingClient(next, t.TempDir(), "test:model", time.Hour)
+	req := NewUserRequest("", "feed", GenerationOptions{})
+
+	resp, err := client.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, Usage{PromptTokens: 10, OutputTokens: 2}, resp.Usage)
+
+	resp, err = client.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, Usage{}, resp.Usage, "a cached response should not be billed again")
+}
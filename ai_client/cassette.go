@@ -0,0 +1,225 @@
+package aiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// ErrNoRecording reports that a replaying CassetteClient has no recording of a request.
+var ErrNoRecording = errors.New("no recording for request")
+
+// CassetteMode selects whether a CassetteClient records or replays.
+type CassetteMode string
+
+const (
+	// CassetteRecord sends requests to the wrapped client and records them with their responses.
+	CassetteRecord CassetteMode = "record"
+
+	// CassetteReplay serves recorded responses without calling any backend.
+	CassetteReplay CassetteMode = "replay"
+)
+
+// Interaction is a recorded request and the response it received.
+type Interaction struct {
+	// Request is the request as sent by the caller.
+	Request Request `json:"request"`
+
+	// Response is the response returned by the backend.
+	Response Response `json:"response"`
+}
+
+// cassette is the content of a cassette file.
+type cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// CassetteClient is a GenAIClient that records requests and responses to a fixture
+// file, or replays them from it offline. Replayed requests must match a recording
+// exactly, including the prompt, options and schema; any other request fails with
+// ErrNoRecording. It is safe for concurrent use.
+type CassetteClient struct {
+	// next is the client whose responses are recorded. It is nil in replay mode.
+	next GenAIClient
+
+	// path is the cassette file.
+	path string
+
+	// mode selects recording or replaying.
+	mode CassetteMode
+
+	// mu guards interactions and writes to the cassette file.
+	mu sync.Mutex
+
+	// interactions holds the recordings in the order they were made.
+	interactions []Interaction
+}
+
+// NewCassetteClient creates a new instance of CassetteClient.
+// In record mode, recordings already in the file are kept and a request that is
+// recorded again replaces its previous recording.
+// Parameters:
+//   - path: The cassette file.
+//   - mode: CassetteRecord or CassetteReplay.
+//   - next: The client to record. It is not used in replay mode and may be nil.
+//
+// Returns:
+//   - *CassetteClient: A pointer to the newly created CassetteClient instance.
+//   - error: An error if the mode is unknown, the file cannot be read, or next is missing in record mode.
+func NewCassetteClient(path string, mode CassetteMode, next GenAIClient) (*CassetteClient, error) {
+	switch mode {
+	case CassetteRecord:
+		if next == nil {
+			return nil, fmt.Errorf("recording a cassette requires a client")
+		}
+	case CassetteReplay:
+	default:
+		return nil, fmt.Errorf("unsupported cassette mode: %s", mode)
+	}
+
+	c := &CassetteClient{
+		next: next,
+		path: path,
+		mode: mode,
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		var cas cassette
+		if err := json.Unmarshal(data, &cas); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		c.interactions = cas.Interactions
+	case mode == CassetteRecord && errors.Is(err, fs.ErrNotExist):
+		// The cassette is created on the first recording.
+	default:
+		return nil, fmt.Errorf("failed to read cassette %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Send replays the recorded response of the request, or in record mode sends the
+// request to the wrapped client and records the response.
+// Parameters:
+//   - ctx: The context of the request.
+//   - req: The request to send.
+//
+// Returns:
+//   - *Response: The recorded or generated response.
+//   - error: An error wrapping ErrNoRecording if there is no recording to replay,
+//     or an error if the wrapped client fails or the cassette cannot be written.
+func (c *CassetteClient) Send(ctx context.Context, req Request) (*Response, error) {
+	return c.send(req, func() (*Response, error) {
+		return c.next.Send(ctx, req)
+	}, nil)
+}
+
+// SendStream replays the recorded response of the request, passing it to onChunk at
+// once, or in record mode streams the request from the wrapped client and records the response.
+// Parameters:
+//   - ctx: The context of the request.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order.
+//
+// Returns:
+//   - *Response: The recorded or generated response.
+//   - error: An error wrapping ErrNoRecording if there is no recording to replay,
+//     or an error if the wrapped client or onChunk fails or the cassette cannot be written.
+func (c *CassetteClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	return c.send(req, func() (*Response, error) {
+		return SendStream(ctx, c.next, req, onChunk)
+	}, onChunk)
+}
+
+// send replays the request, passing the recorded response to onChunk if it is not nil,
+// or in record mode calls generate and records its response.
+func (c *CassetteClient) send(req Request, generate func() (*Response, error), onChunk func(string) error) (*Response, error) {
+	key, err := req.key()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if c.mode == CassetteReplay {
+		c.mu.Lock()
+		i := c.find(key)
+		var resp Response
+		if i >= 0 {
+			resp = c.interactions[i].Response
+		}
+		c.mu.Unlock()
+		if i < 0 {
+			return nil, fmt.Errorf("cassette %s: %w: %s", c.path, ErrNoRecording, summarizeRequest(req))
+		}
+		if onChunk != nil {
+			if err := onChunk(resp.Text); err != nil {
+				return nil, err
+			}
+		}
+		return &resp, nil
+	}
+
+	resp, err := generate()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interaction := Interaction{Request: req, Response: *resp}
+	if i := c.find(key); i >= 0 {
+		c.interactions[i] = interaction
+	} else {
+		c.interactions = append(c.interactions, interaction)
+	}
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// find returns the index of the recording whose request has key, or -1 if there is none.
+// The caller must hold mu.
+func (c *CassetteClient) find(key []byte) int {
+	for i, interaction := range c.interactions {
+		recorded, err := interaction.Request.key()
+		if err == nil && string(recorded) == string(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes all recordings to the cassette file. The caller must hold mu.
+func (c *CassetteClient) save() error {
+	// HTML is left unescaped so that recorded pages stay readable in the fixture.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cassette{Interactions: c.interactions}); err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// summarizeRequest describes a request in an error message by the start of its last message.
+func summarizeRequest(req Request) string {
+	if len(req.Messages) == 0 {
+		return "request without messages"
+	}
+	text := []rune(req.Messages[len(req.Messages)-1].Text)
+	if len(text) > 80 {
+		return fmt.Sprintf("%q...", string(text[:80]))
+	}
+	return fmt.Sprintf("%q", string(text))
+}
@@ -0,0 +1,73 @@
+package aiclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassetteClient_RecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	req := NewUserRequest("system", "feed", GenerationOptions{MaxOutputTokens: 100})
+	req.ResponseSchema = testSummarySchema
+
+	next := &sequenceClient{text: `[{"heading":"h","summary":"s"}]`}
+	recorder, err := NewCassetteClient(path, CassetteRecord, next)
+	assert.NoError(t, err)
+	recorded, err := recorder.Send(context.Background(), req)
+	assert.NoError(t, err)
+	_, err = recorder.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, next.calls)
+
+	player, err := NewCassetteClient(path, CassetteReplay, nil)
+	assert.NoError(t, err)
+	assert.Len(t, player.interactions, 1, "recording a request again should replace the previous recording")
+
+	replayed, err := player.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+
+	other := NewUserRequest("system", "another feed", GenerationOptions{MaxOutputTokens: 100})
+	_, err = player.Send(context.Background(), other)
+	assert.ErrorIs(t, err, ErrNoRecording)
+	assert.ErrorContains(t, err, "another feed")
+}
+
+func TestCassetteClient_Replay_WithoutDocuments(t *testing.T) {
+	// A cassette recorded before requests carried documents.
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"interactions":[{
+		"request":{"messages":[{"role":"user","text":"feed"}],"options":{}},
+		"response":{"text":"summary","backend":"test:model"}
+	}]}`), 0o644))
+	player, err := NewCassetteClient(path, CassetteReplay, nil)
+	assert.NoError(t, err)
+
+	req := NewUserRequest("", "feed", GenerationOptions{})
+	req.Documents = []Document{{Title: "Item", Text: "Text."}}
+	resp, err := player.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "summary", resp.Text)
+
+	// Requests of documents alone are told apart by the documents.
+	docsOnly := Request{Documents: req.Documents}
+	_, err = player.Send(context.Background(), docsOnly)
+	assert.ErrorIs(t, err, ErrNoRecording)
+}
+
+func TestNewCassetteClient_Errors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+
+	_, err := NewCassetteClient(missing, CassetteReplay, nil)
+	assert.ErrorContains(t, err, "failed to read cassette")
+
+	_, err = NewCassetteClient(missing, CassetteRecord, nil)
+	assert.ErrorContains(t, err, "requires a client")
+
+	_, err = NewCassetteClient(missing, "rewind", &sequenceClient{})
+	assert.ErrorContains(t, err, "unsupported cassette mode")
+}
@@ -0,0 +1,114 @@
+package aiclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// continuationPrompt asks the model to continue a truncated response.
+const continuationPrompt = "Your previous response was cut off. Continue exactly where it stopped, without repeating or commenting on anything."
+
+// ContinuationClient is a GenAIClient that completes responses truncated at the output
+// token limit. It sends the partial response back as an assistant message and asks the
+// model to continue, up to a number of times, and joins the parts. Continuations are
+// requested without the response schema, as they are fragments of the original response.
+type ContinuationClient struct {
+	// next is the client whose truncated responses are continued.
+	next GenAIClient
+
+	// maxContinuations is the number of continuation requests per request.
+	maxContinuations int
+}
+
+// NewContinuationClient creates a new instance of ContinuationClient.
+// Parameters:
+//   - next: The client whose truncated responses are continued.
+//   - maxContinuations: The number of continuation requests per request.
+//
+// Returns:
+//   - *ContinuationClient: A pointer to the newly created ContinuationClient instance.
+func NewContinuationClient(next GenAIClient, maxContinuations int) *ContinuationClient {
+	return &ContinuationClient{
+		next:             next,
+		maxContinuations: maxContinuations,
+	}
+}
+
+// Describe describes the wrapped client.
+func (c *ContinuationClient) Describe() string {
+	return Describe(c.next)
+}
+
+// Send sends the request to the wrapped client, continuing a truncated response.
+// Parameters:
+//   - ctx: The context of the request.
+//   - req: The request to send.
+//
+// Returns:
+//   - *Response: The complete response, with the usage of all requests.
+//   - error: An error if a request fails, or a FinishError wrapping ErrTruncated
+//     with the joined parts if the response is still truncated after all continuations.
+func (c *ContinuationClient) Send(ctx context.Context, req Request) (*Response, error) {
+	return c.send(req, func(r Request) (*Response, error) {
+		return c.next.Send(ctx, r)
+	})
+}
+
+// SendStream streams the request from the wrapped client, streaming the continuations
+// of a truncated response to onChunk as well.
+// Parameters:
+//   - ctx: The context of the request.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order.
+//
+// Returns:
+//   - *Response: The complete response, with the usage of all requests.
+//   - error: An error if a request fails, or a FinishError wrapping ErrTruncated
+//     with the joined parts if the response is still truncated after all continuations.
+func (c *ContinuationClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	return c.send(req, func(r Request) (*Response, error) {
+		return SendStream(ctx, c.next, r, onChunk)
+	})
+}
+
+// send calls send with the request and with continuation requests while the response is truncated.
+func (c *ContinuationClient) send(req Request, send func(Request) (*Response, error)) (*Response, error) {
+	total := &Response{}
+	next := req
+	for i := 0; ; i++ {
+		resp, err := send(next)
+		var finishErr *FinishError
+		truncated := errors.As(err, &finishErr) && finishErr.Reason == FinishReasonMaxTokens && finishErr.Partial != nil && finishErr.Partial.Text != ""
+		if err != nil && !truncated {
+			return nil, err
+		}
+		if truncated {
+			resp = finishErr.Partial
+		}
+
+		total.Text += resp.Text
+		total.Backend = resp.Backend
+		total.Usage = total.Usage.Add(resp.Usage)
+		total.FinishReason = resp.FinishReason
+		if !truncated {
+			return total, nil
+		}
+		if i >= c.maxContinuations {
+			finishErr.Partial = total
+			return nil, finishErr
+		}
+		next = continuationRequest(req, total.Text)
+	}
+}
+
+// continuationRequest returns a request asking the model to continue the partial response text.
+func continuationRequest(req Request, text string) Request {
+	cont := req
+	cont.Messages = append(slices.Clone(req.Messages),
+		Message{Role: RoleAssistant, Text: text},
+		Message{Role: RoleUser, Text: continuationPrompt},
+	)
+	cont.ResponseSchema = nil
+	return cont
+}
@@ -0,0 +1,68 @@
+package aiclient
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// truncatingClient returns its parts in order, each but the last truncated.
+type truncatingClient struct {
+	parts    []string
+	requests []Request
+}
+
+func (c *truncatingClient) Send(_ context.Context, req Request) (*Response, error) {
+	c.requests = append(c.requests, req)
+	i := len(c.requests) - 1
+	resp := &Response{Text: c.parts[i], Backend: "test:model", Usage: Usage{PromptTokens: 10, OutputTokens: 5}, FinishReason: FinishReasonStop}
+	if i < len(c.parts)-1 {
+		return nil, finishError("test API", FinishReasonMaxTokens, "", resp)
+	}
+	return resp, nil
+}
+
+func TestContinuationClient_Send(t *testing.T) {
+	next := &truncatingClient{parts: []string{`[{"heading":"h",`, `"summary":"s"}`, `]`}}
+	client := NewContinuationClient(next, 2)
+
+	req := NewUserRequest("system", "feed", GenerationOptions{})
+	req.ResponseSchema = testSummarySchema
+	resp, err := client.Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"heading":"h","summary":"s"}]`, resp.Text)
+	assert.Equal(t, Usage{PromptTokens: 30, OutputTokens: 15}, resp.Usage)
+	assert.Equal(t, FinishReasonStop, resp.FinishReason)
+
+	assert.Len(t, next.requests, 3)
+	cont := next.requests[2]
+	assert.Nil(t, cont.ResponseSchema, "continuations are requested without the schema")
+	assert.Equal(t, []Message{
+		{Role: RoleUser, Text: "feed"},
+		{Role: RoleAssistant, Text: `[{"heading":"h","summary":"s"}`},
+		{Role: RoleUser, Text: continuationPrompt},
+	}, cont.Messages)
+	assert.Len(t, req.Messages, 1, "the original request must not be modified")
+}
+
+func TestContinuationClient_Send_StillTruncated(t *testing.T) {
+	next := &truncatingClient{parts: []string{"a", "b", "c", "d"}}
+	client := NewContinuationClient(next, 2)
+
+	_, err := client.Send(context.Background(), NewUserRequest("", "feed", GenerationOptions{}))
+	assert.ErrorIs(t, err, ErrTruncated)
+	var finishErr *FinishError
+	assert.ErrorAs(t, err, &finishErr)
+	assert.Equal(t, "abc", finishErr.Partial.Text)
+	assert.Len(t, next.requests, 3)
+}
+
+func TestContinuationClient_Send_OtherErrors(t *testing.T) {
+	next := &staticClient{err: finishError("test API", FinishReasonSafety, "SAFETY", &Response{Text: "partial"})}
+	client := NewContinuationClient(next, 2)
+
+	_, err := client.Send(context.Background(), Request{})
+	assert.ErrorIs(t, err, ErrSafetyBlocked)
+	assert.Equal(t, 1, next.calls, "only truncation is continued")
+}
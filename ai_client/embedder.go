@@ -0,0 +1,154 @@
+package aiclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sync/atomic"
+)
+
+// Embedder defines an interface for clients that compute vector embeddings of texts,
+// e.g. to find duplicate or related summaries.
+type Embedder interface {
+	// Embed computes the embeddings of texts. Large inputs are split into batches
+	// the backend accepts, so texts may have any length.
+	// Parameters:
+	//   - ctx: The context of the requests. Cancelling it aborts the remaining batches.
+	//   - texts: The texts to embed.
+	// Returns:
+	//   - [][]float32: One vector per text, in the order of texts.
+	//   - error: An error if any batch fails.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+
+	// Dimensions returns the length of the vectors returned by Embed. It is 0 if the
+	// model is unknown and nothing has been embedded yet.
+	Dimensions() int
+}
+
+// NewEmbedder creates a new embedder of the specified type.
+// The model and dimensionality are taken from cfg; the Gemini backend is selected
+// like NewGenAIClient does. "openai" is configured through OPENAI_BASE_URL,
+// OPENAI_API_KEY and OPENAI_EMBEDDING_MODEL, so it also works with local servers
+// that expose an OpenAI compatible /v1/embeddings endpoint.
+// Parameters:
+//   - ctx: The context used to set up the embedder.
+//   - kind: Type of embedder to create. One of "gemini" or "openai".
+//   - cfg: The embedder settings. cfg.Model names the embedding model.
+//
+// Returns:
+//   - Embedder: A new instance of the embedder.
+//   - error: An error if the type is not supported or the embedder cannot be set up.
+func NewEmbedder(ctx context.Context, kind string, cfg Config) (Embedder, error) {
+	switch kind {
+	case "gemini":
+		clientConfig, err := cfg.geminiClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		return NewGeminiEmbedder(ctx, modelOrDefault(cfg.Model, defaultGeminiEmbeddingModel), cfg.EmbeddingDimensions, clientConfig)
+	case "openai":
+		return NewOpenAIEmbedder(
+			getEnv("OPENAI_BASE_URL", defaultOpenAIBaseURL),
+			os.Getenv("OPENAI_API_KEY"),
+			modelOrDefault(cfg.Model, getEnv("OPENAI_EMBEDDING_MODEL", defaultOpenAIEmbeddingModel)),
+			cfg.EmbeddingDimensions,
+		), nil
+	default:
+		return nil, fmt.Errorf("unsupported embedding API type: %s", kind)
+	}
+}
+
+// knownDimensions lists the default dimensionality of common embedding models.
+var knownDimensions = map[string]int{
+	"gemini-embedding-001":            3072,
+	"text-embedding-004":              768,
+	"text-embedding-005":              768,
+	"text-multilingual-embedding-002": 768,
+	"text-embedding-3-small":          1536,
+	"text-embedding-3-large":          3072,
+	"text-embedding-ada-002":          1536,
+}
+
+// dimensions tracks the dimensionality of an embedder. It starts at the requested or
+// known dimensionality of the model and is learned from the first response otherwise.
+type dimensions struct {
+	n atomic.Int64
+}
+
+// newDimensions returns the dimensionality of model, preferring requested when it is set.
+func newDimensions(model string, requested int) *dimensions {
+	d := &dimensions{}
+	if requested > 0 {
+		d.n.Store(int64(requested))
+	} else {
+		d.n.Store(int64(knownDimensions[model]))
+	}
+	return d
+}
+
+// get returns the dimensionality, or 0 if it is not known yet.
+func (d *dimensions) get() int {
+	return int(d.n.Load())
+}
+
+// observe records the length of returned vectors if the dimensionality was not known.
+func (d *dimensions) observe(vectors [][]float32) {
+	if len(vectors) > 0 {
+		d.n.CompareAndSwap(0, int64(len(vectors[0])))
+	}
+}
+
+// embedInBatches embeds texts in batches of at most batchSize texts.
+// Parameters:
+//   - ctx: The context of the requests. Batches are not started once it is cancelled.
+//   - texts: The texts to embed.
+//   - batchSize: The maximum number of texts per request.
+//   - embed: Embeds a single batch.
+//
+// Returns:
+//   - [][]float32: One vector per text, in the order of texts.
+//   - error: An error if a batch fails or returns the wrong number of vectors.
+func embedInBatches(ctx context.Context, texts []string, batchSize int, embed func(context.Context, []string) ([][]float32, error)) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch := texts[start:min(start+batchSize, len(texts))]
+		batchVectors, err := embed(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(batchVectors) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d: %w", len(batch), len(batchVectors), ErrMalformedOutput)
+		}
+		vectors = append(vectors, batchVectors...)
+	}
+	return vectors, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between two vectors, from -1 for
+// opposite to 1 for identical directions. It is 0 if either vector is zero or their
+// lengths differ.
+// Parameters:
+//   - a: The first vector.
+//   - b: The second vector.
+//
+// Returns:
+//   - float64: The cosine similarity.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,170 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+func TestOpenAIEmbedder_Embed(t *testing.T) {
+	var batches [][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		var req openAIEmbeddingRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "local-model", req.Model)
+		assert.Zero(t, req.Dimensions)
+		batches = append(batches, req.Input)
+
+		// Return the embeddings in reverse order to check they are sorted by index.
+		var data []string
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, fmt.Sprintf(`{"index":%d,"embedding":[%d,0.5,0.25]}`, i, len(strings.TrimPrefix(req.Input[i], "text"))))
+		}
+		_, err := w.Write([]byte(`{"data":[` + strings.Join(data, ",") + `]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	texts := make([]string, openAIEmbeddingBatchSize+2)
+	for i := range texts {
+		texts[i] = "text" + strings.Repeat("x", i%3)
+	}
+
+	embedder := NewOpenAIEmbedder(ts.URL+"/v1", "test-key", "local-model", 0)
+	assert.Zero(t, embedder.Dimensions(), "the dimensionality of an unknown model is learned from the response")
+
+	vectors, err := embedder.Embed(context.Background(), texts)
+	assert.NoError(t, err)
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], openAIEmbeddingBatchSize)
+	assert.Len(t, batches[1], 2)
+	assert.Len(t, vectors, len(texts))
+	assert.Equal(t, []float32{0, 0.5, 0.25}, vectors[0])
+	assert.Equal(t, []float32{2, 0.5, 0.25}, vectors[len(texts)-1])
+	assert.Equal(t, 3, embedder.Dimensions())
+}
+
+func TestOpenAIEmbedder_Dimensions(t *testing.T) {
+	var gotReq openAIEmbeddingRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"data":[{"index":0,"embedding":[1,0]}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	assert.Equal(t, 1536, NewOpenAIEmbedder(ts.URL, "", "text-embedding-3-small", 0).Dimensions())
+
+	embedder := NewOpenAIEmbedder(ts.URL, "", "text-embedding-3-small", 2)
+	assert.Equal(t, 2, embedder.Dimensions())
+	_, err := embedder.Embed(context.Background(), []string{"text"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, gotReq.Dimensions)
+}
+
+func TestOpenAIEmbedder_Embed_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{
+			name:    "rate limited",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"message":"slow down"}}`,
+			wantErr: ErrRateLimited,
+		},
+		{
+			name:    "missing embeddings",
+			status:  http.StatusOK,
+			body:    `{"data":[]}`,
+			wantErr: ErrMalformedOutput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, err := w.Write([]byte(tt.body))
+				assert.NoError(t, err)
+			}))
+			defer ts.Close()
+
+			_, err := NewOpenAIEmbedder(ts.URL, "", "model", 0).Embed(context.Background(), []string{"text"})
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGeminiEmbedder_Embed(t *testing.T) {
+	var gotBody map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/test-model:batchEmbedContents", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		_, err := w.Write([]byte(`{"embeddings":[{"values":[1,0]},{"values":[0,1]}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	embedder, err := NewGeminiEmbedder(context.Background(), "test-model", 2, &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: ts.URL},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, embedder.Dimensions())
+
+	vectors, err := embedder.Embed(context.Background(), []string{"first", "second"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+
+	requests := gotBody["requests"].([]any)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, float64(2), requests[0].(map[string]any)["outputDimensionality"])
+}
+
+func TestNewEmbedder_Unsupported(t *testing.T) {
+	_, err := NewEmbedder(context.Background(), "anthropic", Config{})
+	assert.ErrorContains(t, err, "unsupported embedding API type")
+}
+
+func TestFakeEmbedder(t *testing.T) {
+	embedder := NewFakeEmbedder(64)
+	assert.Equal(t, 64, embedder.Dimensions())
+
+	vectors, err := embedder.Embed(context.Background(), []string{
+		"New phone launched with a larger battery",
+		"New phone launched with a bigger battery",
+		"Central bank raises interest rates",
+		"新型スマートフォンを発表、バッテリーを大容量化",
+		"新型スマートフォン発表 バッテリー大容量化",
+	})
+	assert.NoError(t, err)
+
+	again, err := embedder.Embed(context.Background(), []string{"New phone launched with a larger battery"})
+	assert.NoError(t, err)
+	assert.Equal(t, vectors[0], again[0], "embeddings should be deterministic")
+	assert.InDelta(t, 1, CosineSimilarity(vectors[0], vectors[0]), 1e-6)
+
+	assert.Greater(t, CosineSimilarity(vectors[0], vectors[1]), CosineSimilarity(vectors[0], vectors[2]))
+	assert.Greater(t, CosineSimilarity(vectors[3], vectors[4]), 0.7)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, -1, CosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	assert.InDelta(t, 0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Zero(t, CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+	assert.Zero(t, CosineSimilarity([]float32{1}, []float32{1, 0}))
+}
@@ -0,0 +1,139 @@
+package aiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Error classes shared by all backends. Errors returned by a GenAIClient wrap one
+// of them where the cause is known, so callers can test with errors.Is.
+var (
+	// ErrRateLimited reports that the quota of the backend is exhausted (HTTP 429). It is transient.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrUnavailable reports a server side failure (HTTP 5xx). It is transient.
+	ErrUnavailable = errors.New("service unavailable")
+
+	// ErrEmptyResponse reports that the backend answered without any content. It is transient.
+	ErrEmptyResponse = errors.New("empty response")
+
+	// ErrSafetyBlocked reports that the backend refused to generate content, e.g. because of safety filters.
+	// Finish reasons of the response, such as truncation, are reported by FinishError.
+	ErrSafetyBlocked = errors.New("blocked by safety filters")
+
+	// ErrMalformedOutput reports a response that does not follow the requested response schema.
+	ErrMalformedOutput = errors.New("malformed output")
+
+	// ErrUnauthorized reports missing or invalid credentials (HTTP 401, 403). It is permanent.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrInvalidArgument reports a request the backend rejects, such as an unknown model (HTTP 400, 404, 422). It is permanent.
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// APIError is an error response returned by a backend.
+// It unwraps to the error class matching its status code.
+type APIError struct {
+	// Backend names the API that returned the error.
+	Backend string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message is the error message returned by the backend.
+	Message string
+
+	// RetryAfter is the delay the backend asked for before retrying, or zero if none was given.
+	RetryAfter time.Duration
+}
+
+// Error returns a string representation of the APIError.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s returned status code %d: %s", e.Backend, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the error class of the status code, or nil if the status is not classified.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrUnavailable
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusBadRequest, e.StatusCode == http.StatusNotFound, e.StatusCode == http.StatusUnprocessableEntity:
+		return ErrInvalidArgument
+	default:
+		return nil
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// rate limiting, server errors, empty responses and timeouts.
+// Parameters:
+//   - err: The error returned by a GenAIClient.
+//
+// Returns:
+//   - bool: true if the request may succeed when sent again.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable) || errors.Is(err, ErrEmptyResponse) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryAfter returns the delay the backend asked for in err, or zero if none was given.
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter parses the value of a Retry-After header given in seconds.
+// It returns zero if the value is empty or not a number of seconds.
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// convertGeminiError converts an error of the genai SDK to an APIError, taking the
+// retry delay from the RetryInfo details. Other errors are returned unchanged.
+func convertGeminiError(err error) error {
+	var genaiErr genai.APIError
+	if !errors.As(err, &genaiErr) {
+		return err
+	}
+
+	apiErr := &APIError{
+		Backend:    "Gemini API",
+		StatusCode: genaiErr.Code,
+		Message:    genaiErr.Message,
+	}
+	for _, detail := range genaiErr.Details {
+		if detail["@type"] != "type.googleapis.com/google.rpc.RetryInfo" {
+			continue
+		}
+		if delay, ok := detail["retryDelay"].(string); ok {
+			if d, parseErr := time.ParseDuration(delay); parseErr == nil {
+				apiErr.RetryAfter = d
+			}
+		}
+	}
+	return apiErr
+}
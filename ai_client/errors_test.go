@@ -0,0 +1,71 @@
+package aiclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+func TestAPIError_Unwrap(t *testing.T) {
+	tests := []struct {
+		status    int
+		want      error
+		retryable bool
+	}{
+		{status: http.StatusTooManyRequests, want: ErrRateLimited, retryable: true},
+		{status: http.StatusInternalServerError, want: ErrUnavailable, retryable: true},
+		{status: http.StatusServiceUnavailable, want: ErrUnavailable, retryable: true},
+		{status: http.StatusUnauthorized, want: ErrUnauthorized},
+		{status: http.StatusForbidden, want: ErrUnauthorized},
+		{status: http.StatusBadRequest, want: ErrInvalidArgument},
+		{status: http.StatusNotFound, want: ErrInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &APIError{Backend: "test", StatusCode: tt.status})
+			assert.ErrorIs(t, err, tt.want)
+			assert.Equal(t, tt.retryable, IsRetryable(err))
+		})
+	}
+
+	assert.False(t, IsRetryable(errors.New("unknown")))
+}
+
+func TestConvertGeminiError(t *testing.T) {
+	err := convertGeminiError(genai.APIError{
+		Code:    http.StatusTooManyRequests,
+		Message: "quota exceeded",
+		Status:  "RESOURCE_EXHAUSTED",
+		Details: []map[string]any{
+			{"@type": "type.googleapis.com/google.rpc.QuotaFailure"},
+			{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "17s"},
+		},
+	})
+
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 17*time.Second, retryAfter(err))
+
+	plain := errors.New("plain")
+	assert.Same(t, plain, convertGeminiError(plain))
+}
+
+func TestOpenAIClient_Send_RetryAfter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"slow down"}}`))
+	}))
+	defer ts.Close()
+
+	_, err := NewOpenAIClient(ts.URL, "", "model").Send(t.Context(), NewUserRequest("", "hello", GenerationOptions{}))
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 5*time.Second, retryAfter(err))
+	assert.Contains(t, err.Error(), "slow down")
+}
@@ -0,0 +1,308 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultExtractiveSentences is the number of sentences extracted per document
+	// when none is configured.
+	defaultExtractiveSentences = 3
+
+	// extractiveModel names the algorithm in the Backend of responses.
+	extractiveModel = "textrank"
+
+	// textRankDamping is the damping factor of the TextRank iteration.
+	textRankDamping = 0.85
+
+	// textRankIterations bounds the number of TextRank iterations.
+	textRankIterations = 100
+
+	// textRankTolerance stops the iteration once the scores change less than this.
+	textRankTolerance = 1e-6
+
+	// maxHeadingRunes bounds the length of headings taken from the first sentence.
+	maxHeadingRunes = 40
+
+	// noContentSummary is the summary of a document without text, as the default system prompt requires.
+	noContentSummary = "情報不足"
+)
+
+// sentenceTerminators are the characters that end a sentence in Japanese and English text.
+const sentenceTerminators = "。！？!?．"
+
+// sentenceClosers are closing quotes and brackets kept with the sentence they follow.
+const sentenceClosers = "」』）)\"'”’】"
+
+// ExtractiveClient summarizes documents offline, without a model, by extracting their
+// most central sentences with TextRank. It needs no API key or network access, so it
+// works in air-gapped environments and as the last fallback when every provider is down.
+type ExtractiveClient struct {
+	// sentences is the maximum number of sentences extracted per document.
+	sentences int
+}
+
+// extractiveSummary is a single summary of the response, in the shape of DefaultResponseSchema.
+type extractiveSummary struct {
+	Heading string `json:"heading"`
+	Summary string `json:"summary"`
+}
+
+// NewExtractiveClient creates a new instance of ExtractiveClient.
+// Parameters:
+//   - sentences: The maximum number of sentences extracted per document, or 0 for the default.
+//
+// Returns:
+//   - *ExtractiveClient: A pointer to the newly created ExtractiveClient instance.
+func NewExtractiveClient(sentences int) *ExtractiveClient {
+	if sentences <= 0 {
+		sentences = defaultExtractiveSentences
+	}
+	return &ExtractiveClient{sentences: sentences}
+}
+
+// Describe returns the algorithm and the number of sentences extracted per document.
+func (e *ExtractiveClient) Describe() string {
+	return fmt.Sprintf("%s?sentences=%d", backendName("extractive", extractiveModel), e.sentences)
+}
+
+// Send summarizes the documents of the request, or the text of its last user message
+// if it has none. The response is a JSON array with a {heading, summary} object per
+// document, the same shape the model backends return for the default response schema.
+// The heading is the document title, or its first sentence if it has none, and the
+// summary joins the extracted sentences in their original order.
+// Parameters:
+//   - ctx: The context of the request. Send fails if it is cancelled.
+//   - req: The documents to summarize.
+//
+// Returns:
+//   - *Response: The summaries as a JSON array.
+//   - error: An error if the request has nothing to summarize or ctx is cancelled.
+func (e *ExtractiveClient) Send(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	documents := req.Documents
+	if len(documents) == 0 {
+		for i := len(req.Messages) - 1; i >= 0; i-- {
+			if req.Messages[i].Role == RoleUser {
+				documents = []Document{{Text: req.Messages[i].Text}}
+				break
+			}
+		}
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("extractive summarizer: nothing to summarize: %w", ErrInvalidArgument)
+	}
+
+	summaries := make([]extractiveSummary, 0, len(documents))
+	for _, document := range documents {
+		summaries = append(summaries, e.summarize(document))
+	}
+
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(summaries); err != nil {
+		return nil, fmt.Errorf("failed to encode summaries: %w", err)
+	}
+	return &Response{
+		Text:         strings.TrimSuffix(buf.String(), "\n"),
+		Backend:      backendName("extractive", extractiveModel),
+		FinishReason: FinishReasonStop,
+	}, nil
+}
+
+// summarize extracts the summary of a single document.
+func (e *ExtractiveClient) summarize(document Document) extractiveSummary {
+	sentences := SplitSentences(document.Text)
+	heading := strings.TrimSpace(document.Title)
+	if heading == "" && len(sentences) > 0 {
+		heading = truncateRunes(sentences[0], maxHeadingRunes)
+	}
+	if len(sentences) == 0 {
+		return extractiveSummary{Heading: heading, Summary: noContentSummary}
+	}
+
+	scores := textRank(sentences)
+	order := make([]int, len(sentences))
+	for i := range order {
+		order[i] = i
+	}
+	// Rank by score, keeping earlier sentences first on ties.
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	selected := order[:min(e.sentences, len(order))]
+	sort.Ints(selected)
+
+	var summary strings.Builder
+	for n, i := range selected {
+		if n > 0 && !endsFullWidth(sentences[selected[n-1]]) {
+			summary.WriteByte(' ')
+		}
+		summary.WriteString(sentences[i])
+	}
+	return extractiveSummary{Heading: heading, Summary: summary.String()}
+}
+
+// SplitSentences splits text into sentences. Sentences end at Japanese and English
+// terminators such as 。！？ and !?, at a period followed by a space, and at line breaks.
+// Closing quotes and brackets right after a terminator stay with the sentence.
+// Parameters:
+//   - text: The plain text to split.
+//
+// Returns:
+//   - []string: The trimmed, non-empty sentences in order.
+func SplitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+	flush := func() {
+		if sentence := strings.TrimSpace(current.String()); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' || r == '\r' {
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+
+		end := strings.ContainsRune(sentenceTerminators, r)
+		if r == '.' {
+			// A period ends a sentence only before whitespace, so "3.5" and "e.g.x" stay intact.
+			end = i+1 == len(runes) || unicode.IsSpace(runes[i+1])
+		}
+		if !end {
+			continue
+		}
+		for i+1 < len(runes) && (strings.ContainsRune(sentenceClosers, runes[i+1]) || strings.ContainsRune(sentenceTerminators, runes[i+1])) {
+			i++
+			current.WriteRune(runes[i])
+		}
+		flush()
+	}
+	flush()
+	return sentences
+}
+
+// textRank scores sentences by their centrality in the graph of sentence similarities.
+// Parameters:
+//   - sentences: The sentences to score.
+//
+// Returns:
+//   - []float64: The score of each sentence.
+func textRank(sentences []string) []float64 {
+	n := len(sentences)
+	features := make([]map[string]bool, n)
+	for i, sentence := range sentences {
+		features[i] = map[string]bool{}
+		for _, feature := range textFeatures(sentence) {
+			features[i][feature] = true
+		}
+	}
+
+	weights := make([][]float64, n)
+	totals := make([]float64, n)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			w := sentenceSimilarity(features[i], features[j])
+			weights[i][j], weights[j][i] = w, w
+			totals[i] += w
+			totals[j] += w
+		}
+	}
+
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iteration := 0; iteration < textRankIterations; iteration++ {
+		next := make([]float64, n)
+		delta := 0.0
+		for i := 0; i < n; i++ {
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				if weights[j][i] > 0 {
+					sum += weights[j][i] / totals[j] * scores[j]
+				}
+			}
+			next[i] = 1 - textRankDamping + textRankDamping*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+		scores = next
+		if delta < textRankTolerance {
+			break
+		}
+	}
+	return scores
+}
+
+// sentenceSimilarity is the similarity of two sentences defined by TextRank: the number
+// of shared features normalized by the logarithms of the sentence lengths.
+func sentenceSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for feature := range a {
+		if b[feature] {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+	return float64(shared) / (math.Log(float64(len(a))+1) + math.Log(float64(len(b))+1))
+}
+
+// textFeatures splits text into lower-cased words, and words of more than one character
+// outside the ASCII range into character bigrams, since scripts such as Japanese do not
+// separate words with spaces.
+func textFeatures(text string) []string {
+	var features []string
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		runes := []rune(word)
+		if len(runes) < 2 || runes[0] < unicode.MaxASCII {
+			features = append(features, word)
+			continue
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			features = append(features, string(runes[i:i+2]))
+		}
+	}
+	return features
+}
+
+// endsFullWidth reports whether a sentence ends with a character outside the ASCII range,
+// such as 。, in which case the next sentence follows without a space.
+func endsFullWidth(sentence string) bool {
+	r, _ := utf8.DecodeLastRuneInString(sentence)
+	return r >= utf8.RuneSelf
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with an ellipsis.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,86 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "japanese",
+			text: "新製品を発表した。価格は3.5万円！発売日は？「来月です。」と担当者は話した",
+			want: []string{"新製品を発表した。", "価格は3.5万円！", "発売日は？", "「来月です。」", "と担当者は話した"},
+		},
+		{
+			name: "english",
+			text: "Go 1.25 was released. It adjusts GOMAXPROCS, e.g.in containers! Really?! Yes.",
+			want: []string{"Go 1.25 was released.", "It adjusts GOMAXPROCS, e.g.in containers!", "Really?!", "Yes."},
+		},
+		{
+			name: "line breaks",
+			text: "見出し\n\n本文の一文目。本文の二文目。\r\n",
+			want: []string{"見出し", "本文の一文目。", "本文の二文目。"},
+		},
+		{
+			name: "empty",
+			text: " \n ",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SplitSentences(tt.text))
+		})
+	}
+}
+
+func TestExtractiveClient_Send(t *testing.T) {
+	client := NewExtractiveClient(2)
+	resp, err := client.Send(context.Background(), Request{Documents: []Document{
+		{
+			Title: "Go 1.25 リリース",
+			Text: "Go チームは Go 1.25 を公開した。" +
+				"Go 1.25 ではコンテナ環境で GOMAXPROCS を自動調整する。" +
+				"会場では軽食が振る舞われた。" +
+				"Go 1.25 は実験的なガベージコレクタも導入した。",
+		},
+		{Title: "本文なし"},
+		{Text: "Release notes are out. The new release improves the garbage collector. The new release ships today."},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "extractive:textrank", resp.Backend)
+	assert.Equal(t, FinishReasonStop, resp.FinishReason)
+	assert.Equal(t, Usage{}, resp.Usage)
+
+	var summaries []map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(resp.Text), &summaries))
+	assert.Len(t, summaries, 3)
+
+	assert.Equal(t, "Go 1.25 リリース", summaries[0]["heading"])
+	assert.NotContains(t, summaries[0]["summary"], "軽食", "the off-topic sentence should not be extracted")
+	assert.Equal(t, 2, strings.Count(summaries[0]["summary"], "。"))
+
+	assert.Equal(t, map[string]string{"heading": "本文なし", "summary": noContentSummary}, summaries[1])
+
+	assert.Equal(t, "Release notes are out.", summaries[2]["heading"], "the first sentence is the heading of an untitled document")
+	assert.Equal(t, "The new release improves the garbage collector. The new release ships today.", summaries[2]["summary"])
+}
+
+func TestExtractiveClient_Send_Messages(t *testing.T) {
+	resp, err := NewExtractiveClient(0).Send(context.Background(), NewUserRequest("system", "一文目です。二文目です。", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"heading":"一文目です。","summary":"一文目です。二文目です。"}]`, resp.Text)
+
+	_, err = NewExtractiveClient(0).Send(context.Background(), Request{})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
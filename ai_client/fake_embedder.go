@@ -0,0 +1,77 @@
+package aiclient
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+)
+
+// FakeEmbedder is a deterministic Embedder for tests that runs offline.
+// It hashes the words of a text, and each pair of adjacent characters of words in
+// scripts without spaces such as Japanese, into a normalized vector. Texts sharing
+// many words therefore have a high cosine similarity, and equal texts identical vectors.
+type FakeEmbedder struct {
+	// dimensions is the length of the vectors.
+	dimensions int
+}
+
+// NewFakeEmbedder creates a new instance of FakeEmbedder.
+// Parameters:
+//   - dimensions: The length of the vectors. Must be positive.
+//
+// Returns:
+//   - *FakeEmbedder: A pointer to the newly created FakeEmbedder instance.
+func NewFakeEmbedder(dimensions int) *FakeEmbedder {
+	return &FakeEmbedder{dimensions: dimensions}
+}
+
+// Embed computes the embeddings of texts.
+// Parameters:
+//   - ctx: The context of the request. Embed fails if it is cancelled.
+//   - texts: The texts to embed.
+//
+// Returns:
+//   - [][]float32: One normalized vector per text, or a zero vector for texts without words.
+//   - error: The context error if ctx is cancelled.
+func (f *FakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vectors = append(vectors, f.embed(text))
+	}
+	return vectors, nil
+}
+
+// Dimensions returns the length of the vectors returned by Embed.
+func (f *FakeEmbedder) Dimensions() int {
+	return f.dimensions
+}
+
+// embed hashes the features of text into a normalized vector.
+func (f *FakeEmbedder) embed(text string) []float32 {
+	vector := make([]float32, f.dimensions)
+	for _, feature := range textFeatures(text) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		sum := h.Sum64()
+		sign := float32(1)
+		if sum&1 == 1 {
+			sign = -1
+		}
+		vector[(sum>>1)%uint64(f.dimensions)] += sign
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
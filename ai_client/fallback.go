@@ -0,0 +1,125 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"feed-summarizer/jsonify"
+)
+
+// FallbackEntry is a backend of a FallbackClient.
+type FallbackEntry struct {
+	// Name identifies the backend in error messages, e.g. "gemini:gemini-2.5-flash".
+	Name string
+
+	// Client sends the requests to the backend.
+	Client GenAIClient
+}
+
+// FallbackClient is a GenAIClient that tries an ordered list of backends. A request
+// moves on to the next backend when the current one is rate limited or unavailable,
+// blocks the content, times out or returns malformed output: output that does not
+// follow the response schema, or contains no JSON when the request expects it. Other errors, such as invalid credentials, are returned immediately.
+// The Backend of the response tells which entry produced it.
+type FallbackClient struct {
+	// entries holds the backends in the order they are tried.
+	entries []FallbackEntry
+}
+
+// NewFallbackClient creates a new instance of FallbackClient.
+// Parameters:
+//   - entries: The backends in the order they are tried. At least one is required.
+//
+// Returns:
+//   - *FallbackClient: A pointer to the newly created FallbackClient instance.
+//   - error: An error if no entries are given.
+func NewFallbackClient(entries ...FallbackEntry) (*FallbackClient, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("fallback client requires at least one backend")
+	}
+	return &FallbackClient{entries: entries}, nil
+}
+
+// Describe describes the backends in the order they are tried, separated by commas.
+func (f *FallbackClient) Describe() string {
+	descriptions := make([]string, len(f.entries))
+	for i, entry := range f.entries {
+		descriptions[i] = Describe(entry.Client)
+	}
+	return strings.Join(descriptions, ",")
+}
+
+// Send sends the request to each backend in turn until one succeeds.
+// Parameters:
+//   - ctx: The context of the request. No further backend is tried once it is done.
+//   - req: The request to send.
+//
+// Returns:
+//   - *Response: The response of the first backend that succeeded.
+//   - error: The errors of all backends tried if none succeeded, or the first error that does not warrant a fallback.
+func (f *FallbackClient) Send(ctx context.Context, req Request) (*Response, error) {
+	return f.send(ctx, req, func(client GenAIClient) (*Response, error) {
+		return client.Send(ctx, req)
+	}, nil)
+}
+
+// SendStream streams the request from each backend in turn until one succeeds.
+// Once a backend has passed text to onChunk, its failure is returned without falling
+// back, as the caller may already have used the partial text.
+// Parameters:
+//   - ctx: The context of the request. No further backend is tried once it is done.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order.
+//
+// Returns:
+//   - *Response: The response of the first backend that succeeded.
+//   - error: The errors of all backends tried if none succeeded, or the first error that does not warrant a fallback.
+func (f *FallbackClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	tracker := &chunkTracker{onChunk: onChunk}
+	return f.send(ctx, req, func(client GenAIClient) (*Response, error) {
+		return SendStream(ctx, client, req, tracker.handle)
+	}, tracker)
+}
+
+// send calls each backend in turn with call until one succeeds.
+// If tracker is not nil, no further backend is tried once it has passed text on.
+func (f *FallbackClient) send(ctx context.Context, req Request, call func(GenAIClient) (*Response, error), tracker *chunkTracker) (*Response, error) {
+	var errs error
+	for _, entry := range f.entries {
+		resp, err := call(entry.Client)
+		if err == nil {
+			err = checkResponse(req, resp)
+		}
+		if err == nil {
+			return resp, nil
+		}
+
+		errs = errors.Join(errs, fmt.Errorf("%s: %w", entry.Name, err))
+		if !shouldFallBack(err) || ctx.Err() != nil || (tracker != nil && tracker.emitted) {
+			return nil, errs
+		}
+	}
+	return nil, fmt.Errorf("all backends failed: %w", errs)
+}
+
+// checkResponse returns an error wrapping ErrMalformedOutput if the request has a
+// response schema and the response is not valid JSON, or if the request expects JSON
+// and none can be extracted from the response.
+func checkResponse(req Request, resp *Response) error {
+	switch {
+	case req.ResponseSchema != nil && !json.Valid([]byte(resp.Text)):
+		return fmt.Errorf("response is not valid JSON: %w", ErrMalformedOutput)
+	case req.ExpectJSON && !jsonify.HasJSON(resp.Text):
+		return fmt.Errorf("response contains no JSON: %w", ErrMalformedOutput)
+	}
+	return nil
+}
+
+// shouldFallBack reports whether err warrants trying the next backend:
+// the transient failures reported by IsRetryable, safety and recitation blocks and malformed output.
+func shouldFallBack(err error) bool {
+	return IsRetryable(err) || errors.Is(err, ErrSafetyBlocked) || errors.Is(err, ErrRecitation) || errors.Is(err, ErrMalformedOutput)
+}
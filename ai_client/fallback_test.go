@@ -0,0 +1,117 @@
+package aiclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticClient returns a fixed response or error.
+type staticClient struct {
+	resp  *Response
+	err   error
+	calls int
+}
+
+func (c *staticClient) Send(_ context.Context, _ Request) (*Response, error) {
+	c.calls++
+	return c.resp, c.err
+}
+
+func TestFallbackClient_Send(t *testing.T) {
+	ok := &Response{Text: `[{"heading":"h","summary":"s"}]`, Backend: "ollama:llama3.2"}
+	tests := []struct {
+		name        string
+		first       *staticClient
+		schema      *Schema
+		expectJSON  bool
+		wantBackend string
+		wantErr     error
+	}{
+		{
+			name:        "first backend succeeds",
+			first:       &staticClient{resp: &Response{Text: "[]", Backend: "gemini:flash-lite"}},
+			wantBackend: "gemini:flash-lite",
+		},
+		{
+			name:        "quota exhausted",
+			first:       &staticClient{err: &APIError{StatusCode: http.StatusTooManyRequests}},
+			wantBackend: "ollama:llama3.2",
+		},
+		{
+			name:        "safety block",
+			first:       &staticClient{err: fmt.Errorf("blocked: %w", ErrSafetyBlocked)},
+			wantBackend: "ollama:llama3.2",
+		},
+		{
+			name:        "timeout",
+			first:       &staticClient{err: context.DeadlineExceeded},
+			wantBackend: "ollama:llama3.2",
+		},
+		{
+			name:        "malformed output",
+			first:       &staticClient{resp: &Response{Text: "[{", Backend: "gemini:flash-lite"}},
+			schema:      &Schema{Type: SchemaTypeArray, Items: &Schema{Type: SchemaTypeString}},
+			wantBackend: "ollama:llama3.2",
+		},
+		{
+			name:        "no JSON without a schema",
+			first:       &staticClient{resp: &Response{Text: "I cannot summarize these articles.", Backend: "gemini:flash-lite"}},
+			expectJSON:  true,
+			wantBackend: "ollama:llama3.2",
+		},
+		{
+			name:        "JSON objects without a schema",
+			first:       &staticClient{resp: &Response{Text: `{"heading":"h","summary":"s"}` + "\n" + `{"heading":"h2","summary":"s2"}`, Backend: "gemini:flash-lite"}},
+			expectJSON:  true,
+			wantBackend: "gemini:flash-lite",
+		},
+		{
+			name:    "permanent error",
+			first:   &staticClient{err: &APIError{StatusCode: http.StatusUnauthorized}},
+			wantErr: ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			second := &staticClient{resp: ok}
+			client, err := NewFallbackClient(
+				FallbackEntry{Name: "gemini:flash-lite", Client: tt.first},
+				FallbackEntry{Name: "ollama:llama3.2", Client: second},
+			)
+			assert.NoError(t, err)
+
+			resp, err := client.Send(context.Background(), Request{ResponseSchema: tt.schema, ExpectJSON: tt.expectJSON})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorContains(t, err, "gemini:flash-lite")
+				assert.Equal(t, 0, second.calls)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBackend, resp.Backend)
+		})
+	}
+}
+
+func TestFallbackClient_Send_AllFail(t *testing.T) {
+	client, err := NewFallbackClient(
+		FallbackEntry{Name: "a", Client: &staticClient{err: &APIError{StatusCode: http.StatusServiceUnavailable}}},
+		FallbackEntry{Name: "b", Client: &staticClient{err: fmt.Errorf("no content: %w", ErrEmptyResponse)}},
+	)
+	assert.NoError(t, err)
+
+	_, err = client.Send(context.Background(), Request{})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.ErrorContains(t, err, "all backends failed")
+	assert.ErrorContains(t, err, "b: no content")
+}
+
+func TestNewFallbackClient_NoEntries(t *testing.T) {
+	_, err := NewFallbackClient()
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package aiclient
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FinishReason is the reason a backend stopped generating, normalized across backends.
+type FinishReason string
+
+const (
+	// FinishReasonStop means the model finished its response or hit a stop sequence.
+	FinishReasonStop FinishReason = "stop"
+
+	// FinishReasonMaxTokens means the response was cut off at the output token limit.
+	FinishReasonMaxTokens FinishReason = "max_tokens"
+
+	// FinishReasonSafety means the prompt or response was blocked by safety filters or refused by the model.
+	FinishReasonSafety FinishReason = "safety"
+
+	// FinishReasonRecitation means the response was blocked for reciting training data.
+	FinishReasonRecitation FinishReason = "recitation"
+
+	// FinishReasonOther means generation stopped for another reason reported by the backend.
+	FinishReasonOther FinishReason = "other"
+)
+
+var (
+	// ErrTruncated reports a response cut off at the output token limit.
+	ErrTruncated = errors.New("response truncated")
+
+	// ErrRecitation reports a response blocked for reciting training data.
+	ErrRecitation = errors.New("blocked for recitation")
+)
+
+// FinishError reports that a backend stopped generating for a reason other than
+// finishing its response. It unwraps to ErrTruncated, ErrSafetyBlocked or ErrRecitation
+// depending on the reason.
+type FinishError struct {
+	// Backend names the API that stopped generating.
+	Backend string
+
+	// Reason is the normalized finish reason.
+	Reason FinishReason
+
+	// Detail is the reason as reported by the backend, e.g. "PROHIBITED_CONTENT". It may be empty.
+	Detail string
+
+	// Partial holds the text generated before the backend stopped and the usage of the
+	// request, or nil if there is neither.
+	Partial *Response
+}
+
+// Error returns a string representation of the FinishError.
+func (e *FinishError) Error() string {
+	var msg string
+	switch e.Reason {
+	case FinishReasonMaxTokens:
+		msg = "response was truncated at the output token limit"
+	case FinishReasonSafety:
+		msg = "response was refused or blocked by safety filters"
+	case FinishReasonRecitation:
+		msg = "response was blocked for reciting training data"
+	default:
+		msg = "generation stopped unexpectedly"
+	}
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return fmt.Sprintf("%s: %s", e.Backend, msg)
+}
+
+// Unwrap returns the error class of the finish reason, or nil for FinishReasonOther.
+func (e *FinishError) Unwrap() error {
+	switch e.Reason {
+	case FinishReasonMaxTokens:
+		return ErrTruncated
+	case FinishReasonSafety:
+		return ErrSafetyBlocked
+	case FinishReasonRecitation:
+		return ErrRecitation
+	default:
+		return nil
+	}
+}
+
+// finishError returns a FinishError for an abnormal finish reason, or nil for FinishReasonStop.
+// The partial response is attached if it holds any text or usage.
+func finishError(backend string, reason FinishReason, detail string, partial *Response) error {
+	if reason == FinishReasonStop {
+		return nil
+	}
+	err := &FinishError{Backend: backend, Reason: reason, Detail: detail}
+	if partial != nil && (partial.Text != "" || partial.Usage != Usage{}) {
+		partial.FinishReason = reason
+		err.Partial = partial
+	}
+	return err
+}
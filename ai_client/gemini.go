@@ -2,54 +2,263 @@ package aiclient
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"google.golang.org/genai"
 )
 
+// defaultGeminiModel is the model used when no model is configured.
+const defaultGeminiModel = "gemini-2.5-flash-lite"
+
 // GeminiClient is a client for interacting with the Gemini API.
-// It allows sending prompts to the Gemini model and retrieving generated content.
+// It allows sending prompts to the Gemini model and retrieving generated content,
+// either at once or streamed as it is generated.
+// The underlying genai client is created once and reused for every request.
 type GeminiClient struct {
+	// client is the genai client shared by all requests.
+	client *genai.Client
+
 	// model specifies the Gemini model to use for content generation.
 	model string
+
+	// endpoint identifies the API the requests are sent to, for Describe.
+	endpoint string
 }
 
 // NewGeminiClient creates a new instance of GeminiClient.
 // Parameters:
+//   - ctx: The context used to set up the genai client, e.g. to look up credentials.
 //   - model: A string representing the Gemini model to use.
+//   - config: The genai client configuration selecting the backend and its credentials.
 //
 // Returns:
 //   - *GeminiClient: A pointer to the newly created GeminiClient instance.
-func NewGeminiClient(model string) *GeminiClient {
+//   - error: An error if the genai client cannot be created.
+func NewGeminiClient(ctx context.Context, model string, config *genai.ClientConfig) (*GeminiClient, error) {
+	client, err := genai.NewClient(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
 	return &GeminiClient{
-		model: model,
+		client:   client,
+		model:    model,
+		endpoint: geminiEndpoint(config),
+	}, nil
+}
+
+// geminiEndpoint identifies the API selected by a genai client configuration:
+// the base URL of the Gemini API, or the project and location of Vertex AI.
+func geminiEndpoint(config *genai.ClientConfig) string {
+	if config == nil {
+		return defaultGeminiBaseURL
+	}
+	if config.Backend == genai.BackendVertexAI {
+		endpoint := "vertexai/" + config.Project + "/" + config.Location
+		if config.HTTPOptions.BaseURL != "" {
+			endpoint += "/" + config.HTTPOptions.BaseURL
+		}
+		return endpoint
 	}
+	if config.HTTPOptions.BaseURL != "" {
+		return config.HTTPOptions.BaseURL
+	}
+	return defaultGeminiBaseURL
+}
+
+// Describe returns the model and the API of the client.
+func (g *GeminiClient) Describe() string {
+	return describeBackend("gemini", g.model, g.endpoint)
 }
 
-// Send sends a prompt to the Gemini API and retrieves the generated content.
+// Send sends a request to the Gemini API and retrieves the generated content.
+// The system instruction is passed as SystemInstruction of the request config.
 // Parameters:
-//   - prompt: A string representing the input prompt to send to the Gemini model.
+//   - ctx: The context of the request. Cancelling it aborts the generation.
+//   - req: The system instruction, conversation and generation options.
 //
 // Returns:
-//   - string: The generated content from the Gemini model.
-//   - error: An error if the content generation fails.
-func (g *GeminiClient) Send(prompt string) (string, error) {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		Backend: genai.BackendGeminiAPI,
-	})
-	if err != nil {
-		return "", err
-	}
-
-	result, err := client.Models.GenerateContent(
+//   - *Response: The generated content from the Gemini model.
+//   - error: An error if the content generation fails, or a FinishError if the prompt
+//     was blocked or the model stopped before finishing its response.
+func (g *GeminiClient) Send(ctx context.Context, req Request) (*Response, error) {
+	result, err := g.client.Models.GenerateContent(
 		ctx,
 		g.model,
-		genai.Text(prompt),
-		nil,
+		geminiContents(req.Messages),
+		geminiConfig(req),
 	)
 	if err != nil {
-		return "", err
+		return nil, convertGeminiError(err)
+	}
+	return geminiResponse(backendName("gemini", g.model), result)
+}
+
+// geminiResponse converts a complete GenerateContent response.
+// Parameters:
+//   - backend: The Backend of the response.
+//   - result: The response of the Gemini API.
+//
+// Returns:
+//   - *Response: The generated content.
+//   - error: A FinishError if the prompt was blocked or the model stopped before
+//     finishing its response, or an error if there is no content.
+func geminiResponse(backend string, result *genai.GenerateContentResponse) (*Response, error) {
+	resp := &Response{
+		Text:         result.Text(),
+		Backend:      backend,
+		Usage:        geminiUsage(result.UsageMetadata),
+		FinishReason: FinishReasonStop,
+	}
+	if err := geminiFinishError(result, resp); err != nil {
+		return nil, err
+	}
+	if resp.Text == "" {
+		return nil, fmt.Errorf("Gemini API returned no content: %w", ErrEmptyResponse)
+	}
+	return resp, nil
+}
+
+// geminiUsage converts the usage metadata of a response. Thinking tokens are billed
+// as output and are counted as such.
+func geminiUsage(metadata *genai.GenerateContentResponseUsageMetadata) Usage {
+	if metadata == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens: int(metadata.PromptTokenCount),
+		OutputTokens: int(metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount),
+		CachedTokens: int(metadata.CachedContentTokenCount),
+	}
+}
+
+// SendStream sends a request to the Gemini API with GenerateContentStream and passes
+// the text to onChunk as it arrives.
+// Parameters:
+//   - ctx: The context of the request. Cancelling it aborts the generation.
+//   - req: The system instruction, conversation and generation options.
+//   - onChunk: Called with each piece of text in order. Returning an error aborts the generation.
+//
+// Returns:
+//   - *Response: The whole generated content and its usage.
+//   - error: An error if the content generation fails or onChunk returns an error, or a
+//     FinishError if the prompt was blocked or the model stopped before finishing its response.
+func (g *GeminiClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	resp := &Response{
+		Backend:      backendName("gemini", g.model),
+		FinishReason: FinishReasonStop,
+	}
+	var sb strings.Builder
+	stream := g.client.Models.GenerateContentStream(ctx, g.model, geminiContents(req.Messages), geminiConfig(req))
+	for result, err := range stream {
+		if err != nil {
+			return nil, convertGeminiError(err)
+		}
+		// Every chunk reports the usage so far; the last one holds the totals.
+		if result.UsageMetadata != nil {
+			resp.Usage = geminiUsage(result.UsageMetadata)
+		}
+
+		if text := result.Text(); text != "" {
+			sb.WriteString(text)
+			if err := onChunk(text); err != nil {
+				return nil, err
+			}
+		}
+		// The finish reason arrives with the last chunk.
+		resp.Text = sb.String()
+		if err := geminiFinishError(result, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.Text == "" {
+		return nil, fmt.Errorf("Gemini API returned no content: %w", ErrEmptyResponse)
 	}
+	return resp, nil
+}
 
-	return result.Text(), nil
+// geminiFinishError returns a FinishError if the prompt of a response was blocked or its first
+// candidate stopped for a reason other than finishing, or nil otherwise.
+// Parameters:
+//   - result: The response, or a chunk of a streamed response.
+//   - partial: The text generated so far, attached to the error as partial response.
+//
+// Returns:
+//   - error: The FinishError, or nil.
+func geminiFinishError(result *genai.GenerateContentResponse, partial *Response) error {
+	const backend = "Gemini API"
+	if result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+		return finishError(backend, FinishReasonSafety, "prompt blocked: "+string(result.PromptFeedback.BlockReason), nil)
+	}
+	if len(result.Candidates) == 0 {
+		return nil
+	}
+
+	detail := result.Candidates[0].FinishReason
+	var reason FinishReason
+	switch detail {
+	case "", genai.FinishReasonUnspecified, genai.FinishReasonStop:
+		reason = FinishReasonStop
+	case genai.FinishReasonMaxTokens:
+		reason = FinishReasonMaxTokens
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII, genai.FinishReasonImageSafety:
+		reason = FinishReasonSafety
+	case genai.FinishReasonRecitation:
+		reason = FinishReasonRecitation
+	default:
+		reason = FinishReasonOther
+	}
+	return finishError(backend, reason, string(detail), partial)
+}
+
+// geminiContents maps the messages of a request to genai contents.
+// Assistant messages are sent with the "model" role used by Gemini, and
+// attached images are sent as inline data parts before the text.
+// Parameters:
+//   - messages: The conversation in order.
+//
+// Returns:
+//   - []*genai.Content: The conversation as genai contents.
+func geminiContents(messages []Message) []*genai.Content {
+	contents := make([]*genai.Content, 0, len(messages))
+	for _, m := range messages {
+		var role genai.Role = genai.RoleUser
+		if m.Role == RoleAssistant {
+			role = genai.RoleModel
+		}
+		parts := make([]*genai.Part, 0, len(m.Images)+1)
+		for _, image := range m.Images {
+			parts = append(parts, genai.NewPartFromBytes(image.Data, image.MIMEType))
+		}
+		parts = append(parts, genai.NewPartFromText(m.Text))
+		contents = append(contents, genai.NewContentFromParts(parts, role))
+	}
+	return contents
+}
+
+// geminiConfig maps the system instruction, generation options and response schema of a request
+// to a genai request config.
+// Parameters:
+//   - req: The request to map.
+//
+// Returns:
+//   - *genai.GenerateContentConfig: The request config with only the set options filled in.
+func geminiConfig(req Request) *genai.GenerateContentConfig {
+	config := &genai.GenerateContentConfig{
+		Temperature:     req.Options.Temperature,
+		TopP:            req.Options.TopP,
+		MaxOutputTokens: req.Options.MaxOutputTokens,
+		Seed:            req.Options.Seed,
+		StopSequences:   req.Options.StopSequences,
+	}
+	if req.SystemInstruction != "" {
+		config.SystemInstruction = genai.NewContentFromText(req.SystemInstruction, genai.RoleUser)
+	}
+	if req.ResponseSchema != nil {
+		config.ResponseMIMEType = "application/json"
+		config.ResponseSchema = req.ResponseSchema.geminiSchema()
+	}
+	return config
 }
@@ -0,0 +1,257 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// defaultGeminiBaseURL is the Gemini API endpoint used when no base URL is configured.
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+// GeminiBatchClient submits generateContent requests through the batch mode of the Gemini API.
+// The requests are inlined in the job, so the size of a job is limited to what the API
+// accepts in a single request, currently 20MB.
+// The REST API is called directly as the genai SDK does not map the system instruction
+// of inlined requests.
+type GeminiBatchClient struct {
+	// baseURL is the API root without the version prefix.
+	baseURL string
+
+	// apiKey is the Gemini API key.
+	apiKey string
+
+	// model specifies the Gemini model to use for content generation.
+	model string
+
+	// httpClient is the HTTP client used to call the API.
+	httpClient *http.Client
+}
+
+// geminiBatchRequest is an inlined request of a batch job.
+type geminiBatchRequest struct {
+	Request  geminiGenerateRequest `json:"request"`
+	Metadata geminiBatchMetadata   `json:"metadata"`
+}
+
+// geminiGenerateRequest is the body of a generateContent request.
+type geminiGenerateRequest struct {
+	Contents          []*genai.Content        `json:"contents"`
+	SystemInstruction *genai.Content          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *genai.GenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// geminiBatchMetadata identifies an inlined request and its response.
+type geminiBatchMetadata struct {
+	Key string `json:"key"`
+}
+
+// geminiBatchOperation is the long-running operation of a batch job.
+type geminiBatchOperation struct {
+	Name     string `json:"name"`
+	Metadata struct {
+		State  string `json:"state"`
+		Output struct {
+			InlinedResponses struct {
+				InlinedResponses []geminiInlinedResponse `json:"inlinedResponses"`
+			} `json:"inlinedResponses"`
+		} `json:"output"`
+	} `json:"metadata"`
+	Error *geminiStatus `json:"error,omitempty"`
+}
+
+// geminiInlinedResponse is the response or error of an inlined request.
+type geminiInlinedResponse struct {
+	Response *genai.GenerateContentResponse `json:"response,omitempty"`
+	Error    *geminiStatus                  `json:"error,omitempty"`
+	Metadata geminiBatchMetadata            `json:"metadata"`
+}
+
+// geminiStatus is the error status returned by Google APIs.
+type geminiStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// geminiErrorResponse is the body of a failed Gemini API request.
+type geminiErrorResponse struct {
+	Error *geminiStatus `json:"error"`
+}
+
+// NewGeminiBatchClient creates a new instance of GeminiBatchClient.
+// Parameters:
+//   - baseURL: The API root without the version prefix, e.g. "https://generativelanguage.googleapis.com".
+//   - apiKey: The Gemini API key.
+//   - model: A string representing the Gemini model to use.
+//
+// Returns:
+//   - *GeminiBatchClient: A pointer to the newly created GeminiBatchClient instance.
+func NewGeminiBatchClient(baseURL, apiKey, model string) *GeminiBatchClient {
+	return &GeminiBatchClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+	}
+}
+
+// SubmitBatch creates a batch job with the requests inlined. Each request is tagged
+// with its ID as metadata key, which the API returns with its response.
+// Parameters:
+//   - ctx: The context of the submission.
+//   - requests: The requests with their unique IDs.
+//
+// Returns:
+//   - string: The name of the created job, e.g. "batches/123".
+//   - error: An error if the job cannot be created.
+func (g *GeminiBatchClient) SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	if len(requests) == 0 {
+		return "", fmt.Errorf("batch job without requests: %w", ErrInvalidArgument)
+	}
+	inlined := make([]geminiBatchRequest, 0, len(requests))
+	for _, r := range requests {
+		inlined = append(inlined, geminiBatchRequest{
+			Request:  geminiGenerateRequestOf(r.Request),
+			Metadata: geminiBatchMetadata{Key: r.ID},
+		})
+	}
+	body := map[string]any{
+		"batch": map[string]any{
+			"displayName": "feed-summarizer",
+			"inputConfig": map[string]any{
+				"requests": map[string]any{"requests": inlined},
+			},
+		},
+	}
+
+	url := fmt.Sprintf("%s/v1beta/models/%s:batchGenerateContent", g.baseURL, g.model)
+	res, err := postJSON(ctx, g.httpClient, url, g.header(), body)
+	if err != nil {
+		return "", fmt.Errorf("failed to call batch API: %w", err)
+	}
+	var operation geminiBatchOperation
+	if err := parseGeminiBatchResponse(res, &operation); err != nil {
+		return "", err
+	}
+	return operation.Name, nil
+}
+
+// GetBatch retrieves the status of a batch job, with the inlined responses once it succeeded.
+// Parameters:
+//   - ctx: The context of the request.
+//   - id: The name returned by SubmitBatch.
+//
+// Returns:
+//   - *BatchJob: The status of the job.
+//   - error: An error if the status cannot be retrieved.
+func (g *GeminiBatchClient) GetBatch(ctx context.Context, id string) (*BatchJob, error) {
+	res, err := doRequest(ctx, g.httpClient, http.MethodGet, g.baseURL+"/v1beta/"+id, g.header(), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call batch API: %w", err)
+	}
+	var operation geminiBatchOperation
+	if err := parseGeminiBatchResponse(res, &operation); err != nil {
+		return nil, err
+	}
+
+	job := &BatchJob{ID: id, State: geminiBatchState(operation.Metadata.State)}
+	if operation.Error != nil {
+		job.Message = operation.Error.Message
+	}
+	if job.State != BatchSucceeded {
+		return job, nil
+	}
+
+	backend := backendName("gemini-batch", g.model)
+	for _, inlined := range operation.Metadata.Output.InlinedResponses.InlinedResponses {
+		result := BatchResult{ID: inlined.Metadata.Key}
+		switch {
+		case inlined.Error != nil:
+			result.Err = &APIError{Backend: "Gemini batch API", StatusCode: inlined.Error.Code, Message: inlined.Error.Message}
+		case inlined.Response != nil:
+			result.Response, result.Err = geminiResponse(backend, inlined.Response)
+		default:
+			result.Err = fmt.Errorf("batch request %s has no response: %w", result.ID, ErrEmptyResponse)
+		}
+		job.Results = append(job.Results, result)
+	}
+	return job, nil
+}
+
+// header returns the authentication header of the requests.
+func (g *GeminiBatchClient) header() http.Header {
+	header := http.Header{}
+	header.Set("x-goog-api-key", g.apiKey)
+	return header
+}
+
+// geminiGenerateRequestOf maps a request to the body of a generateContent request,
+// the same way GeminiClient does through the genai SDK.
+// Parameters:
+//   - req: The request to map.
+//
+// Returns:
+//   - geminiGenerateRequest: The request body.
+func geminiGenerateRequestOf(req Request) geminiGenerateRequest {
+	config := geminiConfig(req)
+	return geminiGenerateRequest{
+		Contents:          geminiContents(req.Messages),
+		SystemInstruction: config.SystemInstruction,
+		GenerationConfig: &genai.GenerationConfig{
+			Temperature:      config.Temperature,
+			TopP:             config.TopP,
+			MaxOutputTokens:  config.MaxOutputTokens,
+			Seed:             config.Seed,
+			StopSequences:    config.StopSequences,
+			ResponseMIMEType: config.ResponseMIMEType,
+			ResponseSchema:   config.ResponseSchema,
+		},
+	}
+}
+
+// parseGeminiBatchResponse parses the operation returned by the batch endpoints.
+// Parameters:
+//   - res: The response to parse.
+//   - operation: The operation to unmarshal the body into.
+//
+// Returns:
+//   - error: An APIError if the request failed, or an error if the body cannot be parsed.
+func parseGeminiBatchResponse(res *httpResponse, operation *geminiBatchOperation) error {
+	if res.StatusCode != http.StatusOK {
+		var errResp geminiErrorResponse
+		message := ""
+		if json.Unmarshal(res.Body, &errResp) == nil && errResp.Error != nil {
+			message = errResp.Error.Message
+		}
+		return res.apiError("Gemini batch API", message)
+	}
+	if err := json.Unmarshal(res.Body, operation); err != nil {
+		return fmt.Errorf("failed to parse batch API response: %w", err)
+	}
+	return nil
+}
+
+// geminiBatchState maps the state of a Gemini batch job to a BatchState.
+// The API reports BATCH_STATE_* states, and JOB_STATE_* states in some versions.
+func geminiBatchState(state string) BatchState {
+	switch strings.TrimPrefix(strings.TrimPrefix(state, "BATCH_STATE_"), "JOB_STATE_") {
+	case "RUNNING":
+		return BatchRunning
+	case "SUCCEEDED":
+		return BatchSucceeded
+	case "FAILED":
+		return BatchFailed
+	case "CANCELLED":
+		return BatchCancelled
+	case "EXPIRED":
+		return BatchExpired
+	default: // PENDING, QUEUED
+		return BatchPending
+	}
+}
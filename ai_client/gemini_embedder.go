@@ -0,0 +1,104 @@
+package aiclient
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// defaultGeminiEmbeddingModel is the embedding model used when no model is configured.
+	defaultGeminiEmbeddingModel = "gemini-embedding-001"
+
+	// geminiEmbeddingBatchSize is the maximum number of texts per batchEmbedContents request.
+	geminiEmbeddingBatchSize = 100
+)
+
+// GeminiEmbedder computes embeddings with the Gemini API or Vertex AI.
+type GeminiEmbedder struct {
+	// client is the genai client shared by all requests.
+	client *genai.Client
+
+	// model specifies the embedding model.
+	model string
+
+	// outputDimensionality is the requested vector length, or nil for the model default.
+	outputDimensionality *int32
+
+	// dimensions tracks the length of the returned vectors.
+	dimensions *dimensions
+}
+
+// NewGeminiEmbedder creates a new instance of GeminiEmbedder.
+// Parameters:
+//   - ctx: The context used to set up the genai client, e.g. to look up credentials.
+//   - model: A string representing the embedding model to use.
+//   - dimensions: The requested vector length, or 0 for the model default.
+//   - config: The genai client configuration selecting the backend and its credentials.
+//
+// Returns:
+//   - *GeminiEmbedder: A pointer to the newly created GeminiEmbedder instance.
+//   - error: An error if the genai client cannot be created.
+func NewGeminiEmbedder(ctx context.Context, model string, dimensions int, config *genai.ClientConfig) (*GeminiEmbedder, error) {
+	client, err := genai.NewClient(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create genai client: %w", err)
+	}
+	e := &GeminiEmbedder{
+		client:     client,
+		model:      model,
+		dimensions: newDimensions(model, dimensions),
+	}
+	if dimensions > 0 {
+		n := int32(dimensions)
+		e.outputDimensionality = &n
+	}
+	return e, nil
+}
+
+// Embed computes the embeddings of texts, geminiEmbeddingBatchSize texts per request.
+// Parameters:
+//   - ctx: The context of the requests. Cancelling it aborts the remaining batches.
+//   - texts: The texts to embed.
+//
+// Returns:
+//   - [][]float32: One vector per text, in the order of texts.
+//   - error: An error if any batch fails.
+func (g *GeminiEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := embedInBatches(ctx, texts, geminiEmbeddingBatchSize, g.embedBatch)
+	if err != nil {
+		return nil, err
+	}
+	g.dimensions.observe(vectors)
+	return vectors, nil
+}
+
+// Dimensions returns the length of the vectors returned by Embed.
+func (g *GeminiEmbedder) Dimensions() int {
+	return g.dimensions.get()
+}
+
+// embedBatch embeds a single batch of texts.
+func (g *GeminiEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	contents := make([]*genai.Content, 0, len(texts))
+	for _, text := range texts {
+		contents = append(contents, genai.NewContentFromText(text, genai.RoleUser))
+	}
+
+	result, err := g.client.Models.EmbedContent(ctx, g.model, contents, &genai.EmbedContentConfig{
+		OutputDimensionality: g.outputDimensionality,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed content: %w", convertGeminiError(err))
+	}
+
+	vectors := make([][]float32, 0, len(result.Embeddings))
+	for _, embedding := range result.Embeddings {
+		if embedding == nil {
+			return nil, fmt.Errorf("Gemini API returned an empty embedding: %w", ErrEmptyResponse)
+		}
+		vectors = append(vectors, embedding.Values)
+	}
+	return vectors, nil
+}
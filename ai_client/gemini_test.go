@@ -0,0 +1,166 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+func TestGeminiConfig(t *testing.T) {
+	temperature, seed := float32(0.3), int32(7)
+	config := geminiConfig(NewUserRequest("system", "text", GenerationOptions{
+		Temperature:     &temperature,
+		MaxOutputTokens: 1024,
+		Seed:            &seed,
+		StopSequences:   []string{"END"},
+	}))
+
+	assert.Equal(t, &temperature, config.Temperature)
+	assert.Nil(t, config.TopP)
+	assert.Equal(t, int32(1024), config.MaxOutputTokens)
+	assert.Equal(t, &seed, config.Seed)
+	assert.Equal(t, []string{"END"}, config.StopSequences)
+	assert.Equal(t, "system", config.SystemInstruction.Parts[0].Text)
+
+	assert.Nil(t, geminiConfig(NewUserRequest("", "text", GenerationOptions{})).SystemInstruction)
+	assert.Empty(t, config.ResponseMIMEType)
+}
+
+func TestGeminiConfig_ResponseSchema(t *testing.T) {
+	req := NewUserRequest("", "text", GenerationOptions{})
+	req.ResponseSchema = testSummarySchema
+
+	config := geminiConfig(req)
+	assert.Equal(t, "application/json", config.ResponseMIMEType)
+	assert.Equal(t, genai.TypeArray, config.ResponseSchema.Type)
+}
+
+func TestGeminiContents(t *testing.T) {
+	contents := geminiContents([]Message{
+		{Role: RoleUser, Text: "question"},
+		{Role: RoleAssistant, Text: "answer"},
+	})
+
+	assert.Len(t, contents, 2)
+	assert.Equal(t, genai.RoleUser, contents[0].Role)
+	assert.Equal(t, "question", contents[0].Parts[0].Text)
+	assert.Equal(t, genai.RoleModel, contents[1].Role)
+	assert.Equal(t, "answer", contents[1].Parts[0].Text)
+
+	contents = geminiContents([]Message{
+		{Role: RoleUser, Text: "describe", Images: []Image{{MIMEType: "image/png", Data: []byte("png")}}},
+	})
+	assert.Len(t, contents[0].Parts, 2)
+	assert.Equal(t, &genai.Blob{MIMEType: "image/png", Data: []byte("png")}, contents[0].Parts[0].InlineData)
+	assert.Equal(t, "describe", contents[0].Parts[1].Text)
+}
+
+func TestGeminiClient_Send(t *testing.T) {
+	var gotBody map[string]any
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/test-model:generateContent", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"fake summary"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":50,"candidatesTokenCount":8,"thoughtsTokenCount":4,"cachedContentTokenCount":32}}`))
+		assert.NoError(t, err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	client, err := NewGeminiClient(context.Background(), "test-model", &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: ts.URL},
+	})
+	assert.NoError(t, err)
+
+	result, err := client.Send(context.Background(), NewUserRequest("system", "user text", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "fake summary", result.Text)
+	assert.Equal(t, "gemini:test-model", result.Backend)
+	assert.Equal(t, Usage{PromptTokens: 50, OutputTokens: 12, CachedTokens: 32}, result.Usage)
+
+	systemInstruction, ok := gotBody["systemInstruction"].(map[string]any)
+	assert.True(t, ok, "expected a system instruction in the request")
+	assert.Equal(t, "system", systemInstruction["parts"].([]any)[0].(map[string]any)["text"])
+}
+
+func TestNewGenAIClient_GeminiBaseURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/"+defaultGeminiModel+":generateContent", r.URL.Path)
+		_, err := w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"from gateway"}]}}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	client, err := NewGenAIClient(context.Background(), "gemini", Config{GeminiBaseURL: ts.URL})
+	assert.NoError(t, err)
+
+	result, err := client.Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "from gateway", result.Text)
+}
+
+func TestGeminiClient_Send_FinishReason(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     error
+		wantPartial string
+	}{
+		{
+			name:    "blocked prompt",
+			body:    `{"promptFeedback":{"blockReason":"SAFETY"}}`,
+			wantErr: ErrSafetyBlocked,
+		},
+		{
+			name:    "blocked response",
+			body:    `{"candidates":[{"content":{"role":"model","parts":[{"text":"partial"}]},"finishReason":"PROHIBITED_CONTENT"}]}`,
+			wantErr: ErrSafetyBlocked,
+		},
+		{
+			name:        "truncated response",
+			body:        `{"candidates":[{"content":{"role":"model","parts":[{"text":"[{\"heading\":"}]},"finishReason":"MAX_TOKENS"}]}`,
+			wantErr:     ErrTruncated,
+			wantPartial: `[{"heading":`,
+		},
+		{
+			name:    "recitation",
+			body:    `{"candidates":[{"finishReason":"RECITATION"}]}`,
+			wantErr: ErrRecitation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, err := w.Write([]byte(tt.body))
+				assert.NoError(t, err)
+			}))
+			defer ts.Close()
+
+			client, err := NewGeminiClient(context.Background(), "test-model", &genai.ClientConfig{
+				APIKey:      "test-key",
+				Backend:     genai.BackendGeminiAPI,
+				HTTPOptions: genai.HTTPOptions{BaseURL: ts.URL},
+			})
+			assert.NoError(t, err)
+
+			_, err = client.Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+			assert.ErrorIs(t, err, tt.wantErr)
+			var finishErr *FinishError
+			if assert.ErrorAs(t, err, &finishErr) && tt.wantPartial != "" {
+				assert.Equal(t, tt.wantPartial, finishErr.Partial.Text)
+			}
+		})
+	}
+}
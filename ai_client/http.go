@@ -0,0 +1,103 @@
+package aiclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// httpResponse holds the parts of an HTTP response used by the backends.
+type httpResponse struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Header holds the response headers.
+	Header http.Header
+
+	// Body is the raw response body.
+	Body []byte
+}
+
+// apiError creates an APIError from a failed response, using message if it is not empty
+// and the raw body otherwise. The Retry-After header is taken as the retry delay.
+func (r *httpResponse) apiError(backend, message string) *APIError {
+	if message == "" {
+		message = strings.TrimSpace(string(r.Body))
+	}
+	return &APIError{
+		Backend:    backend,
+		StatusCode: r.StatusCode,
+		Message:    message,
+		RetryAfter: parseRetryAfter(r.Header.Get("Retry-After")),
+	}
+}
+
+// postJSON marshals body as JSON and posts it to url.
+// Parameters:
+//   - ctx: The context of the request. Cancelling it aborts the request.
+//   - c: The HTTP client used to send the request.
+//   - url: The endpoint to post to.
+//   - header: Additional request headers such as authentication. May be nil.
+//   - body: The value to marshal as the request body.
+//
+// Returns:
+//   - *httpResponse: The status code, headers and body of the response.
+//   - error: An error if the request cannot be built or sent, or the response cannot be read.
+func postJSON(ctx context.Context, c *http.Client, url string, header http.Header, body any) (*httpResponse, error) {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return doRequest(ctx, c, http.MethodPost, url, header, "application/json", bytes.NewReader(buf))
+}
+
+// doRequest sends a request and reads the whole response.
+// Parameters:
+//   - ctx: The context of the request. Cancelling it aborts the request.
+//   - c: The HTTP client used to send the request.
+//   - method: The HTTP method.
+//   - url: The endpoint to call.
+//   - header: Additional request headers such as authentication. May be nil.
+//   - contentType: The media type of body. Ignored if body is nil.
+//   - body: The request body, or nil for none.
+//
+// Returns:
+//   - *httpResponse: The status code, headers and body of the response.
+//   - error: An error if the request cannot be built or sent, or the response cannot be read.
+func doRequest(ctx context.Context, c *http.Client, method, url string, header http.Header, contentType string, body io.Reader) (res *httpResponse, err error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", url, err)
+	}
+	defer func() {
+		if closeError := resp.Body.Close(); closeError != nil {
+			err = errors.Join(err, fmt.Errorf("error closing response body: %w", closeError))
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return &httpResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBody,
+	}, nil
+}
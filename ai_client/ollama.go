@@ -0,0 +1,205 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// defaultOllamaHost is the address of a locally running Ollama server.
+	defaultOllamaHost = "http://localhost:11434"
+
+	// defaultOllamaModel is the model used when no model is configured.
+	defaultOllamaModel = "llama3.2"
+)
+
+// OllamaOptions holds the Ollama specific settings of a request.
+type OllamaOptions struct {
+	// KeepAlive controls how long the model stays loaded after the request, e.g. "10m" or "-1".
+	// The server default is used when empty.
+	KeepAlive string
+
+	// NumCtx sets the context window size in tokens. The server default is used when zero.
+	NumCtx int
+}
+
+// OllamaClient is a client for the native chat API of a local Ollama server.
+// Requests never leave the machine running Ollama, which makes it suitable for internal feeds.
+type OllamaClient struct {
+	// baseURL is the address of the Ollama server, e.g. "http://localhost:11434".
+	baseURL string
+
+	// model specifies the model to use for content generation.
+	model string
+
+	// ollamaOptions holds the keep-alive and context window settings.
+	ollamaOptions OllamaOptions
+
+	// httpClient is the HTTP client used to call the API.
+	httpClient *http.Client
+}
+
+// NewOllamaClient creates a new instance of OllamaClient.
+// A scheme-less host such as "127.0.0.1:11434", as accepted by OLLAMA_HOST, is treated as http.
+// Parameters:
+//   - baseURL: The address of the Ollama server.
+//   - model: A string representing the model to use.
+//   - ollamaOptions: The keep-alive and context window settings.
+//
+// Returns:
+//   - *OllamaClient: A pointer to the newly created OllamaClient instance.
+func NewOllamaClient(baseURL, model string, ollamaOptions OllamaOptions) *OllamaClient {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &OllamaClient{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		model:         model,
+		ollamaOptions: ollamaOptions,
+		httpClient: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+	}
+}
+
+// Describe returns the model, the server and the context window of the client.
+func (o *OllamaClient) Describe() string {
+	description := describeBackend("ollama", o.model, o.baseURL)
+	if o.ollamaOptions.NumCtx > 0 {
+		description += fmt.Sprintf("?num_ctx=%d", o.ollamaOptions.NumCtx)
+	}
+	return description
+}
+
+// ollamaMessage is a single message of a chat request or response.
+// Images are sent base64 encoded, as encoding/json does for byte slices.
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  [][]byte `json:"images,omitempty"`
+}
+
+// ollamaModelOptions is the options object of a chat request.
+type ollamaModelOptions struct {
+	NumCtx      int      `json:"num_ctx,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	NumPredict  int32    `json:"num_predict,omitempty"`
+	Seed        *int32   `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// ollamaChatRequest is the request body of the /api/chat endpoint.
+type ollamaChatRequest struct {
+	Model     string              `json:"model"`
+	Messages  []ollamaMessage     `json:"messages"`
+	Stream    bool                `json:"stream"`
+	KeepAlive string              `json:"keep_alive,omitempty"`
+	Options   *ollamaModelOptions `json:"options,omitempty"`
+	Format    *Schema             `json:"format,omitempty"`
+}
+
+// ollamaChatResponse is the response body of the /api/chat endpoint.
+type ollamaChatResponse struct {
+	Message    ollamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason"`
+	Error      string        `json:"error,omitempty"`
+
+	// PromptEvalCount and EvalCount are the numbers of prompt and generated tokens.
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// CheckModel verifies that the configured model is available on the Ollama server.
+// Parameters:
+//   - ctx: The context of the request.
+//
+// Returns:
+//   - error: An error if the server is unreachable or the model has not been pulled.
+func (o *OllamaClient) CheckModel(ctx context.Context) error {
+	res, err := postJSON(ctx, o.httpClient, o.baseURL+"/api/show", nil, map[string]string{"model": o.model})
+	if err != nil {
+		return fmt.Errorf("failed to reach Ollama server at %s: %w", o.baseURL, err)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("model %q is not available on the Ollama server; run `ollama pull %s` first: %w", o.model, o.model, ErrInvalidArgument)
+	default:
+		return fmt.Errorf("failed to check model %q: %w", o.model, res.apiError("Ollama API", ""))
+	}
+}
+
+// Send sends a request to the Ollama chat API.
+// The system instruction is sent as a leading message with the "system" role,
+// and a response schema is passed as the format of the request.
+// Parameters:
+//   - ctx: The context of the request. Cancelling it aborts the request.
+//   - req: The system instruction, conversation and generation options.
+//
+// Returns:
+//   - *Response: The generated content.
+//   - error: An error if the request fails or the server reports an error, or a FinishError if the response was truncated.
+func (o *OllamaClient) Send(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]ollamaMessage, 0, len(req.Messages)+1)
+	if req.SystemInstruction != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: req.SystemInstruction})
+	}
+	for _, m := range req.Messages {
+		message := ollamaMessage{Role: string(m.Role), Content: m.Text}
+		for _, image := range m.Images {
+			message.Images = append(message.Images, image.Data)
+		}
+		messages = append(messages, message)
+	}
+
+	chatReq := ollamaChatRequest{
+		Model:     o.model,
+		Messages:  messages,
+		Stream:    false,
+		KeepAlive: o.ollamaOptions.KeepAlive,
+		Options: &ollamaModelOptions{
+			NumCtx:      o.ollamaOptions.NumCtx,
+			Temperature: req.Options.Temperature,
+			TopP:        req.Options.TopP,
+			NumPredict:  req.Options.MaxOutputTokens,
+			Seed:        req.Options.Seed,
+			Stop:        req.Options.StopSequences,
+		},
+		Format: req.ResponseSchema,
+	}
+
+	res, err := postJSON(ctx, o.httpClient, o.baseURL+"/api/chat", nil, chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Ollama chat API: %w", err)
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.Unmarshal(res.Body, &chatResp); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse Ollama chat response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK || chatResp.Error != "" {
+		return nil, res.apiError("Ollama chat API", chatResp.Error)
+	}
+
+	if chatResp.Message.Content == "" {
+		return nil, fmt.Errorf("ollama chat API returned no content: %w", ErrEmptyResponse)
+	}
+	resp := &Response{
+		Text:         chatResp.Message.Content,
+		Backend:      backendName("ollama", o.model),
+		Usage:        Usage{PromptTokens: chatResp.PromptEvalCount, OutputTokens: chatResp.EvalCount},
+		FinishReason: FinishReasonStop,
+	}
+	if chatResp.DoneReason == "length" {
+		return nil, finishError("Ollama chat API", FinishReasonMaxTokens, "", resp)
+	}
+	return resp, nil
+}
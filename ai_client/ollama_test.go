@@ -0,0 +1,115 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOllamaClient_Send(t *testing.T) {
+	var gotReq ollamaChatRequest
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+
+		_, err := w.Write([]byte(`{"model":"m","message":{"role":"assistant","content":"local summary"},"done":true,"done_reason":"stop","prompt_eval_count":42,"eval_count":7}`))
+		assert.NoError(t, err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	client := NewOllamaClient(ts.URL, "test-model", OllamaOptions{KeepAlive: "10m", NumCtx: 8192})
+	result, err := client.Send(context.Background(), NewUserRequest("system", "user text", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "local summary", result.Text)
+	assert.Equal(t, Usage{PromptTokens: 42, OutputTokens: 7}, result.Usage)
+	assert.Equal(t, "test-model", gotReq.Model)
+	assert.False(t, gotReq.Stream)
+	assert.Equal(t, "10m", gotReq.KeepAlive)
+	assert.Equal(t, &ollamaModelOptions{NumCtx: 8192}, gotReq.Options)
+	assert.Equal(t, []ollamaMessage{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: "user text"},
+	}, gotReq.Messages)
+}
+
+func TestOllamaClient_Send_Images(t *testing.T) {
+	var gotReq map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"message":{"role":"assistant","content":"ok"},"done":true}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	req := Request{Messages: []Message{{Role: RoleUser, Text: "describe", Images: []Image{{MIMEType: "image/png", Data: []byte("png")}}}}}
+	_, err := NewOllamaClient(ts.URL, "model", OllamaOptions{}).Send(context.Background(), req)
+	assert.NoError(t, err)
+
+	message := gotReq["messages"].([]any)[0].(map[string]any)
+	assert.Equal(t, "describe", message["content"])
+	assert.Equal(t, []any{"cG5n"}, message["images"])
+}
+
+func TestOllamaClient_Send_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, err := w.Write([]byte(`{"error":"model requires more system memory"}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	_, err := NewOllamaClient(ts.URL, "model", OllamaOptions{}).Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "model requires more system memory")
+}
+
+func TestOllamaClient_CheckModel(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/show", r.URL.Path)
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["model"] != "available" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"model not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"details":{}}`))
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	assert.NoError(t, NewOllamaClient(ts.URL, "available", OllamaOptions{}).CheckModel(context.Background()))
+
+	err := NewOllamaClient(ts.URL, "missing", OllamaOptions{}).CheckModel(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ollama pull missing")
+}
+
+func TestNewOllamaClient_SchemelessHost(t *testing.T) {
+	client := NewOllamaClient("127.0.0.1:11434", "model", OllamaOptions{})
+	assert.Equal(t, "http://127.0.0.1:11434", client.baseURL)
+}
+
+func TestOllamaClient_Send_ResponseSchema(t *testing.T) {
+	var gotReq map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"message":{"role":"assistant","content":"[]"},"done":true}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	req := NewUserRequest("", "hello", GenerationOptions{})
+	req.ResponseSchema = testSummarySchema
+	_, err := NewOllamaClient(ts.URL, "model", OllamaOptions{}).Send(context.Background(), req)
+	assert.NoError(t, err)
+
+	format := gotReq["format"].(map[string]any)
+	assert.Equal(t, "array", format["type"])
+	assert.Equal(t, "object", format["items"].(map[string]any)["type"])
+}
@@ -0,0 +1,326 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultOpenAIBaseURL is the endpoint used when no base URL is configured.
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+	// defaultOpenAIModel is the model used when no model is configured.
+	defaultOpenAIModel = "gpt-4o-mini"
+
+	// httpClientTimeout defines the timeout duration for requests to HTTP based backends.
+	// Generation over a whole feed can take a while, so it is longer than the page fetch timeout.
+	httpClientTimeout = 5 * time.Minute
+)
+
+// OpenAIClient is a client for APIs compatible with the OpenAI chat completions protocol.
+// Besides OpenAI itself, it works with local servers such as llama.cpp, vLLM or LM Studio
+// that expose the same /v1/chat/completions endpoint.
+type OpenAIClient struct {
+	// baseURL is the API root including the version prefix, e.g. "https://api.openai.com/v1".
+	baseURL string
+
+	// apiKey is sent as a bearer token. It is omitted when empty, as local servers usually do not need it.
+	apiKey string
+
+	// model specifies the model to use for content generation.
+	model string
+
+	// httpClient is the HTTP client used to call the API.
+	httpClient *http.Client
+
+	// promptSchema is set once the server rejected a response_format, after which
+	// response schemas are only given in the system instruction.
+	promptSchema atomic.Bool
+}
+
+// NewOpenAIClient creates a new instance of OpenAIClient.
+// Parameters:
+//   - baseURL: The API root including the version prefix, e.g. "http://localhost:8080/v1".
+//   - apiKey: The API key sent as a bearer token. May be empty for local servers.
+//   - model: A string representing the model to use.
+//
+// Returns:
+//   - *OpenAIClient: A pointer to the newly created OpenAIClient instance.
+func NewOpenAIClient(baseURL, apiKey, model string) *OpenAIClient {
+	return &OpenAIClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		httpClient: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+	}
+}
+
+// openAIMessage is a single message of a chat completions request or response.
+// Content is a string, or a list of openAIContentPart for request messages with images.
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+	Refusal string `json:"refusal,omitempty"`
+}
+
+// openAIContentPart is a text or image part of a chat completions request message.
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+// openAIImageURL is the image of an image part, given as a data URL.
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// openAIChatRequest is the request body of the chat completions endpoint.
+type openAIChatRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature *float32        `json:"temperature,omitempty"`
+	TopP        *float32        `json:"top_p,omitempty"`
+	MaxTokens   int32           `json:"max_tokens,omitempty"`
+	Seed        *int32          `json:"seed,omitempty"`
+	Stop        []string        `json:"stop,omitempty"`
+
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIResponseFormat requests structured output following a JSON schema.
+type openAIResponseFormat struct {
+	Type       string           `json:"type"`
+	JSONSchema openAIJSONSchema `json:"json_schema"`
+}
+
+// openAIJSONSchema is the named schema of a structured output request.
+type openAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+	Strict bool           `json:"strict"`
+}
+
+// openAIChatResponse is the response body of the chat completions endpoint.
+type openAIChatResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// openAIUsage is the token usage of a chat completions response.
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+}
+
+// openAIError is the error object returned by OpenAI compatible servers.
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// Send sends a request to the chat completions endpoint and retrieves the generated content.
+// The system instruction is sent as a leading message with the "system" role.
+// A response schema is enforced with strict structured outputs. As the schema root
+// must be an object, other schemas are wrapped and unwrapped transparently.
+// Servers that reject structured outputs with ErrInvalidArgument and an error naming
+// response_format or json_schema, like some local servers, are sent the request again
+// with the schema in the system instruction, and so are all later requests of the client.
+// Parameters:
+//   - ctx: The context of the request. Cancelling it aborts the request.
+//   - req: The system instruction, conversation and generation options.
+//
+// Returns:
+//   - *Response: The content of the first choice.
+//   - error: An error if the request fails or the server returns no choices, or a
+//     FinishError if the model refused or the response was truncated.
+func (o *OpenAIClient) Send(ctx context.Context, req Request) (*Response, error) {
+	header := http.Header{}
+	if o.apiKey != "" {
+		header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	chatReq, wrapped := o.chatRequest(req)
+	res, err := postJSON(ctx, o.httpClient, o.baseURL+"/chat/completions", header, chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call chat completions API: %w", err)
+	}
+	resp, err := o.chatResponse(res, wrapped)
+	if chatReq.ResponseFormat != nil && rejectsResponseFormat(err) && o.promptSchema.CompareAndSwap(false, true) {
+		log.Printf("%s rejected structured output, giving the response schema in the prompt instead: %v", o.baseURL, err)
+		return o.Send(ctx, req)
+	}
+	return resp, err
+}
+
+// rejectsResponseFormat reports whether err is an invalid argument error caused by the
+// response_format of a request, rather than by another part of it.
+func rejectsResponseFormat(err error) bool {
+	var apiErr *APIError
+	if !errors.Is(err, ErrInvalidArgument) || !errors.As(err, &apiErr) {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "response_format") || strings.Contains(message, "json_schema")
+}
+
+// Describe returns the model and the base URL of the client.
+func (o *OpenAIClient) Describe() string {
+	return describeBackend("openai", o.model, o.baseURL)
+}
+
+// chatRequest maps a request to the body of a chat completions request.
+// The response schema is sent as response_format, or appended to the system
+// message once the server has rejected structured outputs.
+// Parameters:
+//   - req: The system instruction, conversation and generation options.
+//
+// Returns:
+//   - openAIChatRequest: The request body.
+//   - bool: true if the response schema was wrapped in an object, so the response must be unwrapped.
+func (o *OpenAIClient) chatRequest(req Request) (openAIChatRequest, bool) {
+	promptSchema := req.ResponseSchema != nil && o.promptSchema.Load()
+	messages := make([]openAIMessage, 0, len(req.Messages)+1)
+	if system := req.SystemInstruction; system != "" || promptSchema {
+		if promptSchema {
+			system = strings.TrimSpace(system + "\n\n" + req.ResponseSchema.promptInstruction())
+		}
+		messages = append(messages, openAIMessage{Role: "system", Content: system})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, openAIMessage{Role: string(m.Role), Content: openAIContent(m)})
+	}
+
+	chatReq := openAIChatRequest{
+		Model:       o.model,
+		Messages:    messages,
+		Temperature: req.Options.Temperature,
+		TopP:        req.Options.TopP,
+		MaxTokens:   req.Options.MaxOutputTokens,
+		Seed:        req.Options.Seed,
+		Stop:        req.Options.StopSequences,
+	}
+	wrapped := false
+	if req.ResponseSchema != nil && !promptSchema {
+		var schema *Schema
+		schema, wrapped = req.ResponseSchema.objectRoot()
+		chatReq.ResponseFormat = &openAIResponseFormat{
+			Type: "json_schema",
+			JSONSchema: openAIJSONSchema{
+				Name:   "response",
+				Schema: schema.strictJSONSchema(),
+				Strict: true,
+			},
+		}
+	}
+	return chatReq, wrapped
+}
+
+// chatResponse parses a chat completions response.
+// Parameters:
+//   - res: The status code, headers and body of the response.
+//   - wrapped: Whether the response schema was wrapped in an object by chatRequest.
+//
+// Returns:
+//   - *Response: The content of the first choice.
+//   - error: An error if the server failed or returned no choices or content, or a
+//     FinishError if the model refused or the response was truncated.
+func (o *OpenAIClient) chatResponse(res *httpResponse, wrapped bool) (*Response, error) {
+	var chatResp openAIChatResponse
+	if err := json.Unmarshal(res.Body, &chatResp); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse chat response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		message := ""
+		if chatResp.Error != nil {
+			message = chatResp.Error.Message
+		}
+		return nil, res.apiError("chat completions API", message)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("chat completions API returned no choices: %w", ErrEmptyResponse)
+	}
+
+	choice := chatResp.Choices[0]
+	text, _ := choice.Message.Content.(string) // null when the model refused
+	resp := &Response{
+		Text:         text,
+		Backend:      backendName("openai", o.model),
+		FinishReason: FinishReasonStop,
+	}
+	if chatResp.Usage != nil {
+		resp.Usage = Usage{
+			PromptTokens: chatResp.Usage.PromptTokens,
+			OutputTokens: chatResp.Usage.CompletionTokens,
+			CachedTokens: chatResp.Usage.PromptTokensDetails.CachedTokens,
+		}
+	}
+
+	if choice.Message.Refusal != "" {
+		// The refusal is not the response, but its usage is reported.
+		return nil, finishError("chat completions API", FinishReasonSafety, choice.Message.Refusal, &Response{Backend: resp.Backend, Usage: resp.Usage})
+	}
+	switch choice.FinishReason {
+	case "length":
+		// A truncated wrapped response cannot be unwrapped, so it is not offered for continuation.
+		if wrapped {
+			resp = nil
+		}
+		return nil, finishError("chat completions API", FinishReasonMaxTokens, "", resp)
+	case "content_filter":
+		return nil, finishError("chat completions API", FinishReasonSafety, choice.FinishReason, resp)
+	}
+	if resp.Text == "" {
+		return nil, fmt.Errorf("chat completions API returned no content (finish_reason: %s): %w", choice.FinishReason, ErrEmptyResponse)
+	}
+
+	if wrapped {
+		var err error
+		if resp.Text, err = unwrapSchemaResult(resp.Text); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// openAIContent maps a message to the content of a chat completions message: the text alone,
+// or image parts followed by a text part when images are attached.
+// Parameters:
+//   - m: The message to map.
+//
+// Returns:
+//   - any: The string or []openAIContentPart content.
+func openAIContent(m Message) any {
+	if len(m.Images) == 0 {
+		return m.Text
+	}
+	parts := make([]openAIContentPart, 0, len(m.Images)+1)
+	for _, image := range m.Images {
+		parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: dataURL(image)}})
+	}
+	return append(parts, openAIContentPart{Type: "text", Text: m.Text})
+}
+
+// dataURL encodes an image as a base64 data URL.
+func dataURL(image Image) string {
+	return "data:" + image.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(image.Data)
+}
@@ -0,0 +1,312 @@
+package aiclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+const (
+	// openAIBatchEndpoint is the endpoint every request of a batch job is sent to.
+	openAIBatchEndpoint = "/v1/chat/completions"
+
+	// openAIBatchWindow is the completion window of batch jobs. It is the only window OpenAI offers.
+	openAIBatchWindow = "24h"
+
+	// wrappedIDPrefix marks the custom IDs of requests whose response schema was wrapped
+	// in an object, so results can be unwrapped even after the process restarted.
+	wrappedIDPrefix = "w:"
+)
+
+// OpenAIBatchClient submits chat completions requests through the OpenAI Batch API.
+// Requests are uploaded as a JSONL file, and the results are downloaded from the
+// output and error files of the job once it completes.
+type OpenAIBatchClient struct {
+	// chat builds the request bodies and parses the responses of each request.
+	chat *OpenAIClient
+}
+
+// openAIBatchLine is a line of the input file of a batch job.
+type openAIBatchLine struct {
+	CustomID string            `json:"custom_id"`
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Body     openAIChatRequest `json:"body"`
+}
+
+// openAIBatchResultLine is a line of the output or error file of a batch job.
+type openAIBatchResultLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response"`
+	Error *openAIError `json:"error"`
+}
+
+// openAIBatch is the batch object returned by the batches endpoints.
+type openAIBatch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+	Errors       *struct {
+		Data []openAIError `json:"data"`
+	} `json:"errors"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// openAIFile is the file object returned by the files endpoint.
+type openAIFile struct {
+	ID    string       `json:"id"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// NewOpenAIBatchClient creates a new instance of OpenAIBatchClient.
+// Parameters:
+//   - baseURL: The API root including the version prefix, e.g. "https://api.openai.com/v1".
+//   - apiKey: The API key sent as a bearer token.
+//   - model: A string representing the model to use.
+//
+// Returns:
+//   - *OpenAIBatchClient: A pointer to the newly created OpenAIBatchClient instance.
+func NewOpenAIBatchClient(baseURL, apiKey, model string) *OpenAIBatchClient {
+	return &OpenAIBatchClient{chat: NewOpenAIClient(baseURL, apiKey, model)}
+}
+
+// SubmitBatch uploads the requests as a JSONL file and creates a batch job for it.
+// Parameters:
+//   - ctx: The context of the submission.
+//   - requests: The requests with their unique IDs.
+//
+// Returns:
+//   - string: The ID of the created job.
+//   - error: An error if the file cannot be uploaded or the job cannot be created.
+func (o *OpenAIBatchClient) SubmitBatch(ctx context.Context, requests []BatchRequest) (string, error) {
+	if len(requests) == 0 {
+		return "", fmt.Errorf("batch job without requests: %w", ErrInvalidArgument)
+	}
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, r := range requests {
+		body, wrapped := o.chat.chatRequest(r.Request)
+		customID := r.ID
+		if wrapped {
+			customID = wrappedIDPrefix + customID
+		}
+		line := openAIBatchLine{CustomID: customID, Method: http.MethodPost, URL: openAIBatchEndpoint, Body: body}
+		if err := encoder.Encode(line); err != nil {
+			return "", fmt.Errorf("failed to encode batch request %s: %w", r.ID, err)
+		}
+	}
+
+	fileID, err := o.uploadFile(ctx, input.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	res, err := postJSON(ctx, o.chat.httpClient, o.chat.baseURL+"/batches", o.header(), map[string]string{
+		"input_file_id":     fileID,
+		"endpoint":          openAIBatchEndpoint,
+		"completion_window": openAIBatchWindow,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to call batches API: %w", err)
+	}
+	var batch openAIBatch
+	if err := parseOpenAIBatchResponse(res, &batch, &batch.Error); err != nil {
+		return "", err
+	}
+	return batch.ID, nil
+}
+
+// uploadFile uploads the input file of a batch job.
+// Parameters:
+//   - ctx: The context of the upload.
+//   - content: The JSONL content of the file.
+//
+// Returns:
+//   - string: The ID of the uploaded file.
+//   - error: An error if the upload fails.
+func (o *OpenAIBatchClient) uploadFile(ctx context.Context, content []byte) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return "", fmt.Errorf("failed to write upload form: %w", err)
+	}
+	part, err := writer.CreateFormFile("file", "batch.jsonl")
+	if err != nil {
+		return "", fmt.Errorf("failed to write upload form: %w", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		return "", fmt.Errorf("failed to write upload form: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to write upload form: %w", err)
+	}
+
+	res, err := doRequest(ctx, o.chat.httpClient, http.MethodPost, o.chat.baseURL+"/files", o.header(), writer.FormDataContentType(), &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to call files API: %w", err)
+	}
+	var file openAIFile
+	if err := parseOpenAIBatchResponse(res, &file, &file.Error); err != nil {
+		return "", err
+	}
+	return file.ID, nil
+}
+
+// GetBatch retrieves the status of a batch job. Once the job completed, its output
+// and error files are downloaded and parsed into results.
+// Parameters:
+//   - ctx: The context of the request.
+//   - id: The ID returned by SubmitBatch.
+//
+// Returns:
+//   - *BatchJob: The status of the job.
+//   - error: An error if the status or the result files cannot be retrieved.
+func (o *OpenAIBatchClient) GetBatch(ctx context.Context, id string) (*BatchJob, error) {
+	res, err := doRequest(ctx, o.chat.httpClient, http.MethodGet, o.chat.baseURL+"/batches/"+id, o.header(), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call batches API: %w", err)
+	}
+	var batch openAIBatch
+	if err := parseOpenAIBatchResponse(res, &batch, &batch.Error); err != nil {
+		return nil, err
+	}
+
+	job := &BatchJob{ID: id, State: openAIBatchState(batch.Status)}
+	if batch.Errors != nil {
+		messages := make([]string, 0, len(batch.Errors.Data))
+		for _, e := range batch.Errors.Data {
+			messages = append(messages, e.Message)
+		}
+		job.Message = strings.Join(messages, "; ")
+	}
+	if job.State != BatchSucceeded {
+		return job, nil
+	}
+
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		results, err := o.fileResults(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		job.Results = append(job.Results, results...)
+	}
+	return job, nil
+}
+
+// fileResults downloads an output or error file of a batch job and parses its lines.
+// Parameters:
+//   - ctx: The context of the download.
+//   - fileID: The ID of the file.
+//
+// Returns:
+//   - []BatchResult: The result of each line.
+//   - error: An error if the file cannot be downloaded or parsed.
+func (o *OpenAIBatchClient) fileResults(ctx context.Context, fileID string) ([]BatchResult, error) {
+	res, err := doRequest(ctx, o.chat.httpClient, http.MethodGet, o.chat.baseURL+"/files/"+fileID+"/content", o.header(), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call files API: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, res.apiError("files API", "")
+	}
+
+	var results []BatchResult
+	scanner := bufio.NewScanner(bytes.NewReader(res.Body))
+	scanner.Buffer(nil, len(res.Body)+1)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line openAIBatchResultLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, fmt.Errorf("failed to parse batch result file %s: %w", fileID, err)
+		}
+		results = append(results, o.lineResult(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch result file %s: %w", fileID, err)
+	}
+	return results, nil
+}
+
+// lineResult converts a line of a result file into the result of its request.
+func (o *OpenAIBatchClient) lineResult(line openAIBatchResultLine) BatchResult {
+	id, wrapped := strings.CutPrefix(line.CustomID, wrappedIDPrefix)
+	result := BatchResult{ID: id}
+	switch {
+	case line.Response != nil:
+		result.Response, result.Err = o.chat.chatResponse(&httpResponse{
+			StatusCode: line.Response.StatusCode,
+			Body:       line.Response.Body,
+		}, wrapped)
+		if result.Response != nil {
+			result.Response.Backend = backendName("openai-batch", o.chat.model)
+		}
+	case line.Error != nil:
+		result.Err = fmt.Errorf("batch request %s failed: %s", id, line.Error.Message)
+	default:
+		result.Err = fmt.Errorf("batch request %s has no response: %w", id, ErrEmptyResponse)
+	}
+	return result
+}
+
+// header returns the authentication header of the requests.
+func (o *OpenAIBatchClient) header() http.Header {
+	header := http.Header{}
+	if o.chat.apiKey != "" {
+		header.Set("Authorization", "Bearer "+o.chat.apiKey)
+	}
+	return header
+}
+
+// parseOpenAIBatchResponse parses the JSON response of a batches or files endpoint.
+// Parameters:
+//   - res: The response to parse.
+//   - v: The value to unmarshal the body into.
+//   - apiErr: The error object of v, reported if the request failed.
+//
+// Returns:
+//   - error: An APIError if the request failed, or an error if the body cannot be parsed.
+func parseOpenAIBatchResponse(res *httpResponse, v any, apiErr **openAIError) error {
+	if err := json.Unmarshal(res.Body, v); err != nil && res.StatusCode == http.StatusOK {
+		return fmt.Errorf("failed to parse batch API response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		message := ""
+		if *apiErr != nil {
+			message = (*apiErr).Message
+		}
+		return res.apiError("batch API", message)
+	}
+	return nil
+}
+
+// openAIBatchState maps the status of an OpenAI batch to a BatchState.
+func openAIBatchState(status string) BatchState {
+	switch status {
+	case "validating":
+		return BatchPending
+	case "completed":
+		return BatchSucceeded
+	case "failed":
+		return BatchFailed
+	case "cancelled":
+		return BatchCancelled
+	case "expired":
+		return BatchExpired
+	default: // in_progress, finalizing, cancelling
+		return BatchRunning
+	}
+}
@@ -0,0 +1,137 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// defaultOpenAIEmbeddingModel is the embedding model used when no model is configured.
+	defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+
+	// openAIEmbeddingBatchSize is the number of texts per embeddings request. The API accepts
+	// up to 2048 inputs, but also limits the total tokens of a request, which long summaries reach first.
+	openAIEmbeddingBatchSize = 256
+)
+
+// OpenAIEmbedder computes embeddings with APIs compatible with the OpenAI /v1/embeddings endpoint.
+type OpenAIEmbedder struct {
+	// baseURL is the API root including the version prefix, e.g. "https://api.openai.com/v1".
+	baseURL string
+
+	// apiKey is sent as a bearer token. It is omitted when empty.
+	apiKey string
+
+	// model specifies the embedding model.
+	model string
+
+	// requestedDimensions is the requested vector length, or 0 for the model default.
+	requestedDimensions int
+
+	// dimensions tracks the length of the returned vectors.
+	dimensions *dimensions
+
+	// httpClient is the HTTP client used to call the API.
+	httpClient *http.Client
+}
+
+// openAIEmbeddingRequest is the request body of the embeddings endpoint.
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// openAIEmbeddingResponse is the response body of the embeddings endpoint.
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// NewOpenAIEmbedder creates a new instance of OpenAIEmbedder.
+// Parameters:
+//   - baseURL: The API root including the version prefix, e.g. "http://localhost:8080/v1".
+//   - apiKey: The API key sent as a bearer token. May be empty for local servers.
+//   - model: A string representing the embedding model to use.
+//   - dimensions: The requested vector length, or 0 for the model default.
+//
+// Returns:
+//   - *OpenAIEmbedder: A pointer to the newly created OpenAIEmbedder instance.
+func NewOpenAIEmbedder(baseURL, apiKey, model string, dimensions int) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		baseURL:             strings.TrimRight(baseURL, "/"),
+		apiKey:              apiKey,
+		model:               model,
+		requestedDimensions: dimensions,
+		dimensions:          newDimensions(model, dimensions),
+		httpClient: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+	}
+}
+
+// Embed computes the embeddings of texts, openAIEmbeddingBatchSize texts per request.
+// Parameters:
+//   - ctx: The context of the requests. Cancelling it aborts the remaining batches.
+//   - texts: The texts to embed.
+//
+// Returns:
+//   - [][]float32: One vector per text, in the order of texts.
+//   - error: An error if any batch fails.
+func (o *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := embedInBatches(ctx, texts, openAIEmbeddingBatchSize, o.embedBatch)
+	if err != nil {
+		return nil, err
+	}
+	o.dimensions.observe(vectors)
+	return vectors, nil
+}
+
+// Dimensions returns the length of the vectors returned by Embed.
+func (o *OpenAIEmbedder) Dimensions() int {
+	return o.dimensions.get()
+}
+
+// embedBatch embeds a single batch of texts.
+func (o *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	header := http.Header{}
+	if o.apiKey != "" {
+		header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	res, err := postJSON(ctx, o.httpClient, o.baseURL+"/embeddings", header, openAIEmbeddingRequest{
+		Model:      o.model,
+		Input:      texts,
+		Dimensions: o.requestedDimensions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call embeddings API: %w", err)
+	}
+
+	var embResp openAIEmbeddingResponse
+	if err := json.Unmarshal(res.Body, &embResp); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse embeddings response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		message := ""
+		if embResp.Error != nil {
+			message = embResp.Error.Message
+		}
+		return nil, res.apiError("embeddings API", message)
+	}
+
+	// The data is documented to be in input order, but carries the index to be sure.
+	sort.SliceStable(embResp.Data, func(i, j int) bool { return embResp.Data[i].Index < embResp.Data[j].Index })
+	vectors := make([][]float32, 0, len(embResp.Data))
+	for _, data := range embResp.Data {
+		vectors = append(vectors, data.Embedding)
+	}
+	return vectors, nil
+}
@@ -0,0 +1,324 @@
+package aiclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAIClient_Send(t *testing.T) {
+	var gotReq openAIChatRequest
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"summary"},"finish_reason":"stop"}],"usage":{"prompt_tokens":30,"completion_tokens":5,"prompt_tokens_details":{"cached_tokens":10}}}`))
+		assert.NoError(t, err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	client := NewOpenAIClient(ts.URL+"/v1/", "test-key", "test-model")
+	result, err := client.Send(context.Background(), Request{
+		SystemInstruction: "system",
+		Messages: []Message{
+			{Role: RoleUser, Text: "hello"},
+			{Role: RoleAssistant, Text: "partial"},
+			{Role: RoleUser, Text: "continue"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "summary", result.Text)
+	assert.Equal(t, "openai:test-model", result.Backend)
+	assert.Equal(t, Usage{PromptTokens: 30, OutputTokens: 5, CachedTokens: 10}, result.Usage)
+	assert.Equal(t, "test-model", gotReq.Model)
+	assert.Equal(t, []openAIMessage{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: "hello"},
+		{Role: "assistant", Content: "partial"},
+		{Role: "user", Content: "continue"},
+	}, gotReq.Messages)
+}
+
+func TestOpenAIClient_GenerationOptions(t *testing.T) {
+	var gotReq map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	temperature, topP, seed := float32(0.5), float32(0.9), int32(42)
+	client := NewOpenAIClient(ts.URL, "", "model")
+	_, err := client.Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{
+		Temperature:     &temperature,
+		TopP:            &topP,
+		MaxOutputTokens: 256,
+		Seed:            &seed,
+		StopSequences:   []string{"\n\n"},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, gotReq["temperature"])
+	assert.InDelta(t, 0.9, gotReq["top_p"], 1e-6)
+	assert.Equal(t, float64(256), gotReq["max_tokens"])
+	assert.Equal(t, float64(42), gotReq["seed"])
+	assert.Equal(t, []any{"\n\n"}, gotReq["stop"])
+
+	// Unset options must not be sent so the server defaults apply.
+	gotReq = nil
+	_, err = NewOpenAIClient(ts.URL, "", "model").Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.NotContains(t, gotReq, "temperature")
+	assert.NotContains(t, gotReq, "seed")
+}
+
+func TestOpenAIClient_Send_WithoutAPIKey(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, err := w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"local"}}]}`))
+		assert.NoError(t, err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	result, err := NewOpenAIClient(ts.URL, "", "local-model").Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "local", result.Text)
+}
+
+func TestOpenAIClient_Send_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "api error",
+			status:  http.StatusUnauthorized,
+			body:    `{"error":{"message":"invalid api key","type":"invalid_request_error"}}`,
+			wantErr: "invalid api key",
+		},
+		{
+			name:    "non json error",
+			status:  http.StatusBadGateway,
+			body:    "bad gateway",
+			wantErr: "bad gateway",
+		},
+		{
+			name:    "no choices",
+			status:  http.StatusOK,
+			body:    `{"choices":[]}`,
+			wantErr: "no choices",
+		},
+		{
+			name:    "empty content",
+			status:  http.StatusOK,
+			body:    `{"choices":[{"message":{"role":"assistant","content":""},"finish_reason":"stop"}]}`,
+			wantErr: "no content",
+		},
+		{
+			name:    "refusal",
+			status:  http.StatusOK,
+			body:    `{"choices":[{"message":{"role":"assistant","content":"","refusal":"I can't help with that"},"finish_reason":"stop"}]}`,
+			wantErr: "I can't help with that",
+		},
+		{
+			name:    "truncated",
+			status:  http.StatusOK,
+			body:    `{"choices":[{"message":{"role":"assistant","content":"partial"},"finish_reason":"length"}]}`,
+			wantErr: "truncated",
+		},
+		{
+			name:    "malformed response",
+			status:  http.StatusOK,
+			body:    `not json`,
+			wantErr: "failed to parse",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, err := w.Write([]byte(tt.body))
+				assert.NoError(t, err)
+			}))
+			defer ts.Close()
+
+			_, err := NewOpenAIClient(ts.URL, "key", "model").Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestOpenAIClient_Send_RefusalUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "refusal",
+			body: `{"choices":[{"message":{"role":"assistant","content":null,"refusal":"I can't help with that"},"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`,
+		},
+		{
+			name: "content filter",
+			body: `{"choices":[{"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			_, err := NewOpenAIClient(ts.URL, "key", "model").Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+			assert.ErrorIs(t, err, ErrSafetyBlocked)
+			var finishErr *FinishError
+			if assert.ErrorAs(t, err, &finishErr) && assert.NotNil(t, finishErr.Partial, "the usage of a refused request should be reported") {
+				assert.Equal(t, Usage{PromptTokens: 10, OutputTokens: 5}, finishErr.Partial.Usage)
+			}
+		})
+	}
+}
+
+func TestOpenAIClient_Send_EmptyContent(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":""},"finish_reason":"stop"}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	result, err := NewOpenAIClient(ts.URL, "key", "model").Send(context.Background(), NewUserRequest("", "hello", GenerationOptions{}))
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrEmptyResponse)
+	assert.True(t, IsRetryable(err), "an empty response should be retried and fall back like on the other backends")
+}
+
+func TestOpenAIClient_Send_Cancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"late"}}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewOpenAIClient(ts.URL, "", "model").Send(ctx, NewUserRequest("", "hello", GenerationOptions{}))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestOpenAIClient_Send_ResponseSchema(t *testing.T) {
+	var gotReq openAIChatRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"result\":[{\"heading\":\"h\",\"summary\":\"s\"}]}"}}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	req := NewUserRequest("", "hello", GenerationOptions{})
+	req.ResponseSchema = testSummarySchema
+	result, err := NewOpenAIClient(ts.URL, "", "model").Send(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"heading":"h","summary":"s"}]`, result.Text)
+
+	assert.NotNil(t, gotReq.ResponseFormat)
+	assert.Equal(t, "json_schema", gotReq.ResponseFormat.Type)
+	assert.True(t, gotReq.ResponseFormat.JSONSchema.Strict)
+	assert.Equal(t, "object", gotReq.ResponseFormat.JSONSchema.Schema["type"])
+}
+
+func TestOpenAIClient_Send_ResponseSchemaRejected(t *testing.T) {
+	var gotReqs []openAIChatRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var gotReq openAIChatRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		gotReqs = append(gotReqs, gotReq)
+		if gotReq.ResponseFormat != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"response_format json_schema is not supported"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"[{\"heading\":\"h\",\"summary\":\"s\"}]"}}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewOpenAIClient(ts.URL, "", "model")
+	req := NewUserRequest("system", "hello", GenerationOptions{})
+	req.ResponseSchema = testSummarySchema
+	for range 2 {
+		result, err := client.Send(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, `[{"heading":"h","summary":"s"}]`, result.Text, "the prompt-only response should not be unwrapped")
+	}
+
+	if assert.Len(t, gotReqs, 3, "the schema should only be offered as response_format once") {
+		assert.NotNil(t, gotReqs[0].ResponseFormat)
+		for _, gotReq := range gotReqs[1:] {
+			assert.Nil(t, gotReq.ResponseFormat)
+			system, _ := gotReq.Messages[0].Content.(string)
+			assert.True(t, strings.HasPrefix(system, "system\n\nRespond only with JSON"))
+			assert.Contains(t, system, `"heading"`)
+		}
+	}
+}
+
+func TestOpenAIClient_Send_OtherInvalidArgument(t *testing.T) {
+	var gotReqs []openAIChatRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var gotReq openAIChatRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		gotReqs = append(gotReqs, gotReq)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"maximum context length exceeded"}}`))
+	}))
+	defer ts.Close()
+
+	client := NewOpenAIClient(ts.URL, "", "model")
+	req := NewUserRequest("system", "hello", GenerationOptions{})
+	req.ResponseSchema = testSummarySchema
+	for range 2 {
+		_, err := client.Send(context.Background(), req)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		assert.ErrorContains(t, err, "maximum context length exceeded")
+	}
+
+	if assert.Len(t, gotReqs, 2, "errors unrelated to the schema should not be retried") {
+		for _, gotReq := range gotReqs {
+			assert.NotNil(t, gotReq.ResponseFormat, "errors unrelated to the schema should keep response_format")
+		}
+	}
+}
+
+func TestOpenAIClient_Send_Images(t *testing.T) {
+	var gotReq map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&gotReq))
+		_, err := w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	req := Request{Messages: []Message{{Role: RoleUser, Text: "describe", Images: []Image{{MIMEType: "image/png", Data: []byte("png")}}}}}
+	_, err := NewOpenAIClient(ts.URL, "", "model").Send(context.Background(), req)
+	assert.NoError(t, err)
+
+	content := gotReq["messages"].([]any)[0].(map[string]any)["content"]
+	assert.Equal(t, []any{
+		map[string]any{"type": "image_url", "image_url": map[string]any{"url": "data:image/png;base64,cG5n"}},
+		map[string]any{"type": "text", "text": "describe"},
+	}, content)
+}
@@ -0,0 +1,90 @@
+package aiclient
+
+import (
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// defaultGCPLocation is the Google Cloud region of Vertex AI when Config.GCPLocation is empty.
+const defaultGCPLocation = "us-central1"
+
+// GenerationOptions holds the sampling parameters of a Request.
+// Every backend maps them to its own request format. Nil pointers and zero values
+// leave the backend default in place, so only explicitly set parameters are sent.
+type GenerationOptions struct {
+	// Temperature controls the randomness of the output.
+	Temperature *float32 `json:"temperature,omitempty"`
+
+	// TopP is the cumulative probability cutoff for nucleus sampling.
+	TopP *float32 `json:"top_p,omitempty"`
+
+	// MaxOutputTokens is the maximum number of tokens to generate.
+	MaxOutputTokens int32 `json:"max_output_tokens,omitempty"`
+
+	// Seed makes sampling reproducible on backends that support it.
+	// The Anthropic Messages API has no seed parameter and ignores it.
+	Seed *int32 `json:"seed,omitempty"`
+
+	// StopSequences stop generation when any of them is produced.
+	StopSequences []string `json:"stop_sequences,omitempty"`
+}
+
+// Config holds the settings used by NewGenAIClient and NewEmbedder to create a backend.
+type Config struct {
+	// Model overrides the model of the backend. When empty, the model from the
+	// backend's environment variable or its built-in default is used.
+	Model string
+
+	// GCPProjectID is the Google Cloud project used by the Vertex AI backend.
+	// Setting it switches the "gemini" kind from the Gemini API to Vertex AI.
+	GCPProjectID string
+
+	// GCPLocation is the Google Cloud region of the Vertex AI backend, e.g. "us-central1".
+	// defaultGCPLocation is used when empty.
+	GCPLocation string
+
+	// GeminiBaseURL points the "gemini" kind at an alternate HTTP endpoint,
+	// such as a corporate gateway or a local fake server in tests.
+	// The public endpoint of the selected backend is used when empty.
+	GeminiBaseURL string
+
+	// EmbeddingDimensions is the vector length requested by NewEmbedder, for models
+	// that support shortened embeddings. The model default is used when zero.
+	EmbeddingDimensions int
+}
+
+// vertexAI reports whether the "gemini" kind uses Vertex AI instead of the Gemini API.
+func (c Config) vertexAI() bool {
+	return c.GCPProjectID != ""
+}
+
+// geminiClientConfig returns the genai client configuration selected by the config.
+// Vertex AI is selected by GCPProjectID and authenticates with Application Default
+// Credentials, such as a service account, while the Gemini API reads its key from
+// GEMINI_API_KEY. Both honour GeminiBaseURL.
+// Returns:
+//   - *genai.ClientConfig: The configuration for genai.NewClient.
+//   - error: An error if a location is given without a project ID.
+func (c Config) geminiClientConfig() (*genai.ClientConfig, error) {
+	httpOptions := genai.HTTPOptions{BaseURL: c.GeminiBaseURL}
+	if !c.vertexAI() {
+		if c.GCPLocation != "" {
+			return nil, fmt.Errorf("GCP project ID is required to use Vertex AI in location %s", c.GCPLocation)
+		}
+		return &genai.ClientConfig{
+			Backend:     genai.BackendGeminiAPI,
+			HTTPOptions: httpOptions,
+		}, nil
+	}
+	location := c.GCPLocation
+	if location == "" {
+		location = defaultGCPLocation
+	}
+	return &genai.ClientConfig{
+		Backend:     genai.BackendVertexAI,
+		Project:     c.GCPProjectID,
+		Location:    location,
+		HTTPOptions: httpOptions,
+	}, nil
+}
@@ -0,0 +1,194 @@
+package aiclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit is a budget of requests and tokens per minute. A zero value disables the respective limit.
+type RateLimit struct {
+	// RequestsPerMinute is the maximum number of requests started per minute.
+	RequestsPerMinute int
+
+	// TokensPerMinute is the maximum number of estimated prompt and output tokens per minute.
+	TokensPerMinute int
+}
+
+// RateLimiter enforces a RateLimit. It is safe for concurrent use; callers that
+// exceed the budget wait until it is replenished.
+type RateLimiter struct {
+	// requests limits the number of requests, or is nil if unlimited.
+	requests *rate.Limiter
+
+	// tokens limits the number of estimated tokens, or is nil if unlimited.
+	tokens *rate.Limiter
+}
+
+// NewRateLimiter creates a new instance of RateLimiter.
+// Requests are spread evenly over the minute; tokens may be spent in a burst of up to one minute's budget.
+// Parameters:
+//   - limit: The requests and tokens per minute.
+//
+// Returns:
+//   - *RateLimiter: A pointer to the newly created RateLimiter instance.
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	l := &RateLimiter{}
+	if limit.RequestsPerMinute > 0 {
+		l.requests = rate.NewLimiter(rate.Every(time.Minute/time.Duration(limit.RequestsPerMinute)), 1)
+	}
+	if limit.TokensPerMinute > 0 {
+		l.tokens = rate.NewLimiter(rate.Limit(float64(limit.TokensPerMinute)/60), limit.TokensPerMinute)
+	}
+	return l
+}
+
+// Wait blocks until a request of the given number of tokens fits in the budget.
+// A request larger than the whole token budget waits for the full budget.
+// Parameters:
+//   - ctx: The context of the request. Waiting stops when it is done.
+//   - tokens: The estimated number of tokens of the request.
+//
+// Returns:
+//   - error: An error if ctx is done, or would be done, before the budget allows the request.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	if l.requests != nil {
+		if err := l.requests.Wait(ctx); err != nil {
+			return fmt.Errorf("request rate limit: %w", err)
+		}
+	}
+	if l.tokens != nil && tokens > 0 {
+		if err := l.tokens.WaitN(ctx, min(tokens, l.tokens.Burst())); err != nil {
+			return fmt.Errorf("token rate limit: %w", err)
+		}
+	}
+	return nil
+}
+
+// rateLimiters holds the limiters shared by all clients of a provider and model.
+var rateLimiters = struct {
+	sync.Mutex
+	m map[string]*RateLimiter
+}{m: make(map[string]*RateLimiter)}
+
+// SharedRateLimiter returns the limiter shared by every caller of the given provider and model,
+// creating it with limit on first use. Later calls return the same limiter regardless of limit,
+// so concurrent summarizations draw from a single budget.
+// Parameters:
+//   - provider: The API kind, e.g. "gemini".
+//   - model: The model name. An empty name stands for the provider's default model.
+//   - limit: The budget used if the limiter does not exist yet.
+//
+// Returns:
+//   - *RateLimiter: The shared limiter.
+func SharedRateLimiter(provider, model string, limit RateLimit) *RateLimiter {
+	key := provider + "/" + model
+	rateLimiters.Lock()
+	defer rateLimiters.Unlock()
+	if l, ok := rateLimiters.m[key]; ok {
+		return l
+	}
+	l := NewRateLimiter(limit)
+	rateLimiters.m[key] = l
+	return l
+}
+
+// RateLimitedClient is a GenAIClient that waits for a RateLimiter before passing each request on.
+type RateLimitedClient struct {
+	// next is the client whose requests are limited.
+	next GenAIClient
+
+	// limiter holds the budget, possibly shared with other clients.
+	limiter *RateLimiter
+}
+
+// NewRateLimitedClient creates a new instance of RateLimitedClient.
+// Parameters:
+//   - next: The client whose requests are limited.
+//   - limiter: The budget to wait for, possibly shared with other clients.
+//
+// Returns:
+//   - *RateLimitedClient: A pointer to the newly created RateLimitedClient instance.
+func NewRateLimitedClient(next GenAIClient, limiter *RateLimiter) *RateLimitedClient {
+	return &RateLimitedClient{
+		next:    next,
+		limiter: limiter,
+	}
+}
+
+// Describe describes the wrapped client.
+func (r *RateLimitedClient) Describe() string {
+	return Describe(r.next)
+}
+
+// Send waits until the request fits in the budget and sends it to the wrapped client.
+// Parameters:
+//   - ctx: The context of the request. Waiting and sending stop when it is done.
+//   - req: The request to send.
+//
+// Returns:
+//   - *Response: The generated content.
+//   - error: An error if waiting is aborted or the wrapped client fails.
+func (r *RateLimitedClient) Send(ctx context.Context, req Request) (*Response, error) {
+	if err := r.limiter.Wait(ctx, EstimateTokens(req)); err != nil {
+		return nil, err
+	}
+	return r.next.Send(ctx, req)
+}
+
+// SendStream waits until the request fits in the budget and streams it from the wrapped client.
+// Parameters:
+//   - ctx: The context of the request. Waiting and sending stop when it is done.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order.
+//
+// Returns:
+//   - *Response: The whole generated content.
+//   - error: An error if waiting is aborted or the wrapped client fails.
+func (r *RateLimitedClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	if err := r.limiter.Wait(ctx, EstimateTokens(req)); err != nil {
+		return nil, err
+	}
+	return SendStream(ctx, r.next, req, onChunk)
+}
+
+// imageTokenEstimate is the estimated number of tokens of an attached image. Vision models
+// bill an image downscaled to about 1000 pixels at roughly this many tokens or fewer.
+const imageTokenEstimate = 1000
+
+// EstimateTokens estimates the number of tokens a request consumes without calling a tokenizer:
+// one token per four ASCII characters and one per other character, so that text in scripts such
+// as Japanese is not underestimated, imageTokenEstimate per attached image, plus the maximum
+// number of output tokens if set.
+// Parameters:
+//   - req: The request to estimate.
+//
+// Returns:
+//   - int: The estimated number of tokens.
+func EstimateTokens(req Request) int {
+	ascii, other := countChars(req.SystemInstruction)
+	images := 0
+	for _, m := range req.Messages {
+		a, o := countChars(m.Text)
+		ascii += a
+		other += o
+		images += len(m.Images)
+	}
+	return (ascii+3)/4 + other + images*imageTokenEstimate + int(req.Options.MaxOutputTokens)
+}
+
+// countChars returns the number of ASCII and other characters in s.
+func countChars(s string) (ascii, other int) {
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return ascii, other
+}
@@ -0,0 +1,79 @@
+package aiclient
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	req := Request{
+		SystemInstruction: "abcdefgh",
+		Messages:          []Message{{Role: RoleUser, Text: "こんにちは"}},
+		Options:           GenerationOptions{MaxOutputTokens: 100},
+	}
+	assert.Equal(t, 2+5+100, EstimateTokens(req))
+
+	req.Messages[0].Images = []Image{{MIMEType: "image/png"}, {MIMEType: "image/png"}}
+	assert.Equal(t, 2+5+2*imageTokenEstimate+100, EstimateTokens(req))
+}
+
+func TestRateLimiter_Wait_Requests(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{RequestsPerMinute: 1200})
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, limiter.Wait(context.Background(), 0))
+		}()
+	}
+	wg.Wait()
+
+	// The first request starts immediately, the other four every 50ms.
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+}
+
+func TestRateLimiter_Wait_ExceedsDeadline(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{RequestsPerMinute: 1, TokensPerMinute: 1000})
+	assert.NoError(t, limiter.Wait(context.Background(), 5000), "a request larger than the budget waits for the full budget")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, limiter.Wait(ctx, 0), "request rate limit")
+}
+
+func TestRateLimiter_Wait_Tokens(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{TokensPerMinute: 600})
+	assert.NoError(t, limiter.Wait(context.Background(), 600))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorContains(t, limiter.Wait(ctx, 100), "token rate limit")
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	a := SharedRateLimiter("test", "model-a", RateLimit{RequestsPerMinute: 10})
+	assert.Same(t, a, SharedRateLimiter("test", "model-a", RateLimit{RequestsPerMinute: 20}))
+	assert.NotSame(t, a, SharedRateLimiter("test", "model-b", RateLimit{RequestsPerMinute: 10}))
+}
+
+func TestRateLimitedClient_Send(t *testing.T) {
+	next := &sequenceClient{text: "ok"}
+	client := NewRateLimitedClient(next, NewRateLimiter(RateLimit{RequestsPerMinute: 1}))
+
+	resp, err := client.Send(context.Background(), Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp.Text)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.Send(ctx, Request{})
+	assert.Error(t, err)
+	assert.Equal(t, 1, next.calls, "a request over budget must not reach the backend")
+}
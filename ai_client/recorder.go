@@ -0,0 +1,81 @@
+package aiclient
+
+import (
+	"context"
+	"errors"
+)
+
+// UsageRecorder is called with every response a backend returns, including the partial
+// responses of failed requests. It must be safe for concurrent use.
+type UsageRecorder func(ctx context.Context, resp *Response)
+
+// UsageRecordingClient is a GenAIClient that passes the response of every request to a
+// UsageRecorder. Wrapped directly around a backend, below retries, continuations and
+// fallbacks, it sees each attempt, so the tokens of failed attempts and discarded
+// partial responses are recorded as well.
+type UsageRecordingClient struct {
+	// next is the client whose usage is recorded.
+	next GenAIClient
+
+	// record is called with each response.
+	record UsageRecorder
+}
+
+// NewUsageRecordingClient creates a new instance of UsageRecordingClient.
+// Parameters:
+//   - next: The client whose usage is recorded, usually a backend.
+//   - record: Called with each response of next.
+//
+// Returns:
+//   - *UsageRecordingClient: A pointer to the newly created UsageRecordingClient instance.
+func NewUsageRecordingClient(next GenAIClient, record UsageRecorder) *UsageRecordingClient {
+	return &UsageRecordingClient{
+		next:   next,
+		record: record,
+	}
+}
+
+// Describe describes the wrapped client.
+func (u *UsageRecordingClient) Describe() string {
+	return Describe(u.next)
+}
+
+// Send sends the request to the wrapped client and records the usage of its response.
+// Parameters:
+//   - ctx: The context of the request, which is passed to the recorder as well.
+//   - req: The request to send.
+//
+// Returns:
+//   - *Response: The generated content.
+//   - error: An error if the wrapped client fails.
+func (u *UsageRecordingClient) Send(ctx context.Context, req Request) (*Response, error) {
+	resp, err := u.next.Send(ctx, req)
+	u.recordResult(ctx, resp, err)
+	return resp, err
+}
+
+// SendStream streams the request from the wrapped client and records the usage of its response.
+// Parameters:
+//   - ctx: The context of the request, which is passed to the recorder as well.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order.
+//
+// Returns:
+//   - *Response: The whole generated content.
+//   - error: An error if the wrapped client fails.
+func (u *UsageRecordingClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	resp, err := SendStream(ctx, u.next, req, onChunk)
+	u.recordResult(ctx, resp, err)
+	return resp, err
+}
+
+// recordResult records the response of a request, or the partial response of a FinishError.
+func (u *UsageRecordingClient) recordResult(ctx context.Context, resp *Response, err error) {
+	var finishErr *FinishError
+	switch {
+	case err == nil:
+		u.record(ctx, resp)
+	case errors.As(err, &finishErr) && finishErr.Partial != nil:
+		u.record(ctx, finishErr.Partial)
+	}
+}
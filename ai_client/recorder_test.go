@@ -0,0 +1,51 @@
+package aiclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageRecordingClient_Send(t *testing.T) {
+	var recorded []Usage
+	record := func(_ context.Context, resp *Response) {
+		recorded = append(recorded, resp.Usage)
+	}
+
+	// Every part of a response that is still truncated after all continuations is recorded,
+	// although the request fails.
+	next := &truncatingClient{parts: []string{"a", "b", "c", "d"}}
+	client := NewContinuationClient(NewUsageRecordingClient(next, record), 1)
+	_, err := client.Send(context.Background(), NewUserRequest("", "feed", GenerationOptions{}))
+	assert.ErrorIs(t, err, ErrTruncated)
+	assert.Equal(t, []Usage{{PromptTokens: 10, OutputTokens: 5}, {PromptTokens: 10, OutputTokens: 5}}, recorded)
+
+	// Failed attempts without a response record nothing, the successful retry is recorded.
+	recorded = nil
+	retry, _ := newTestRetryClient(NewUsageRecordingClient(&sequenceClient{
+		errs: []error{&APIError{Backend: "test", StatusCode: http.StatusServiceUnavailable}},
+		text: "summary",
+	}, record), 2)
+	resp, err := retry.Send(context.Background(), NewUserRequest("", "feed", GenerationOptions{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "summary", resp.Text)
+	assert.Equal(t, []Usage{{}}, recorded)
+}
+
+func TestUsageRecordingClient_SendStream(t *testing.T) {
+	var recorded []*Response
+	client := NewUsageRecordingClient(&sequenceClient{text: "summary"}, func(_ context.Context, resp *Response) {
+		recorded = append(recorded, resp)
+	})
+
+	var chunks []string
+	resp, err := client.SendStream(context.Background(), NewUserRequest("", "feed", GenerationOptions{}), func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"summary"}, chunks)
+	assert.Equal(t, []*Response{resp}, recorded)
+}
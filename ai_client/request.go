@@ -0,0 +1,152 @@
+package aiclient
+
+import "encoding/json"
+
+// Role identifies the author of a message in a conversation.
+type Role string
+
+const (
+	// RoleUser marks a message written by the user.
+	RoleUser Role = "user"
+
+	// RoleAssistant marks a message previously generated by the model.
+	RoleAssistant Role = "assistant"
+)
+
+// Message is a single turn of a conversation.
+type Message struct {
+	// Role is the author of the message.
+	Role Role `json:"role"`
+
+	// Text is the content of the message.
+	Text string `json:"text"`
+
+	// Images are attached to the message before the text, in order.
+	// Backends send them as image parts, so the model must support vision.
+	Images []Image `json:"images,omitempty"`
+}
+
+// Image is an image attached to a message.
+type Image struct {
+	// MIMEType is the media type of the image, e.g. "image/jpeg".
+	MIMEType string `json:"mime_type"`
+
+	// Data is the encoded image.
+	Data []byte `json:"data"`
+}
+
+// Request is a generation request sent to a GenAIClient.
+// Backends pass SystemInstruction through their dedicated system field
+// instead of prepending it to the user text.
+type Request struct {
+	// SystemInstruction holds the instructions for the model. It is omitted when empty.
+	SystemInstruction string `json:"system_instruction,omitempty"`
+
+	// Messages holds the conversation in order. The last message is usually from the user.
+	Messages []Message `json:"messages"`
+
+	// Options holds the generation parameters of the request.
+	Options GenerationOptions `json:"options"`
+
+	// ResponseSchema, when set, makes the backend return JSON that follows the schema.
+	// Each backend enforces it with its native structured output feature.
+	ResponseSchema *Schema `json:"response_schema,omitempty"`
+
+	// ExpectJSON marks a request whose response must contain JSON that the jsonify package
+	// can extract, even without a ResponseSchema. A FallbackClient tries the next backend
+	// when it does not. It is not sent to the backends and not part of the cache and cassette keys.
+	ExpectJSON bool `json:"-"`
+
+	// Documents holds the plain text of the items to summarize, for backends that work
+	// on the items themselves rather than on the prompt, such as the extractive backend.
+	// Model backends ignore it; the items are included in Messages for them. It is only
+	// part of the cache and cassette keys of requests without messages.
+	Documents []Document `json:"documents,omitempty"`
+}
+
+// Document is an item to summarize, given as plain text.
+type Document struct {
+	// Title is the title of the item. It may be empty.
+	Title string `json:"title,omitempty"`
+
+	// Link is the URL of the item. It may be empty.
+	Link string `json:"link,omitempty"`
+
+	// Text is the plain text content of the item. It may be empty.
+	Text string `json:"text,omitempty"`
+}
+
+// key returns the JSON that identifies the request in the response cache and in cassettes.
+// The Documents of a request with messages are left out, since the messages carry the
+// same items, so that keys computed before Documents existed stay valid.
+func (r Request) key() ([]byte, error) {
+	if len(r.Messages) > 0 {
+		r.Documents = nil
+	}
+	return json.Marshal(r)
+}
+
+// Response is the result of a request sent to a GenAIClient.
+type Response struct {
+	// Text is the generated content.
+	Text string `json:"text"`
+
+	// Backend identifies the API and model that generated the content as "kind:model",
+	// e.g. "gemini:gemini-2.5-flash-lite".
+	Backend string `json:"backend"`
+
+	// Usage is the number of tokens the request consumed, as reported by the backend.
+	Usage Usage `json:"usage"`
+
+	// FinishReason is the reason the backend stopped generating. Responses returned
+	// without an error finished normally; other reasons are reported as a FinishError.
+	FinishReason FinishReason `json:"finish_reason,omitempty"`
+}
+
+// Usage is the number of tokens consumed by one or more requests.
+type Usage struct {
+	// PromptTokens is the number of input tokens, including cached ones.
+	PromptTokens int `json:"prompt_tokens"`
+
+	// OutputTokens is the number of generated tokens, including reasoning tokens.
+	OutputTokens int `json:"output_tokens"`
+
+	// CachedTokens is the number of input tokens served from the backend's prompt cache,
+	// which are usually billed at a lower price.
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// Add returns the sum of u and other.
+// Parameters:
+//   - other: The usage to add.
+//
+// Returns:
+//   - Usage: The total usage.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens: u.PromptTokens + other.PromptTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+		CachedTokens: u.CachedTokens + other.CachedTokens,
+	}
+}
+
+// backendName formats the Backend of a Response.
+func backendName(kind, model string) string {
+	return kind + ":" + model
+}
+
+// NewUserRequest creates a request consisting of a system instruction and a single user message.
+// Parameters:
+//   - systemInstruction: The instructions for the model. May be empty.
+//   - text: The user text.
+//   - options: The generation parameters of the request.
+//
+// Returns:
+//   - Request: The assembled request.
+func NewUserRequest(systemInstruction, text string, options GenerationOptions) Request {
+	return Request{
+		SystemInstruction: systemInstruction,
+		Messages:          []Message{{Role: RoleUser, Text: text}},
+		Options:           options,
+	}
+}
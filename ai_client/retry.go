@@ -0,0 +1,155 @@
+package aiclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures how RetryClient retries transient failures.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+
+	// InitialBackoff is the upper bound of the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the exponentially growing delay between retries.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a retry policy suitable for scheduled runs.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: 2 * time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+// RetryClient is a GenAIClient that retries transient failures of another client
+// with jittered exponential backoff. Rate limiting, server errors, empty responses
+// and timeouts are retried; a retry delay returned by the server takes precedence
+// over the computed backoff. Permanent failures such as ErrUnauthorized or
+// ErrInvalidArgument are returned immediately.
+type RetryClient struct {
+	// next is the client whose requests are retried.
+	next GenAIClient
+
+	// policy configures the number of retries and the backoff.
+	policy RetryPolicy
+
+	// sleep waits for the given duration or until ctx is done. It is replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryClient creates a new instance of RetryClient.
+// Parameters:
+//   - next: The client whose requests are retried.
+//   - policy: The number of retries and the backoff.
+//
+// Returns:
+//   - *RetryClient: A pointer to the newly created RetryClient instance.
+func NewRetryClient(next GenAIClient, policy RetryPolicy) *RetryClient {
+	return &RetryClient{
+		next:   next,
+		policy: policy,
+		sleep:  sleepContext,
+	}
+}
+
+// Describe describes the wrapped client.
+func (r *RetryClient) Describe() string {
+	return Describe(r.next)
+}
+
+// Send sends the request to the wrapped client, retrying transient failures.
+// Parameters:
+//   - ctx: The context of the request. Retrying stops when it is done.
+//   - req: The request to send.
+//
+// Returns:
+//   - *Response: The generated content.
+//   - error: The last error if all attempts fail, or the first permanent error.
+func (r *RetryClient) Send(ctx context.Context, req Request) (*Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := r.next.Send(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if !IsRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		if attempt >= r.policy.MaxRetries {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		if sleepErr := r.sleep(ctx, r.backoff(attempt, err)); sleepErr != nil {
+			return nil, fmt.Errorf("retry aborted: %w (last error: %w)", sleepErr, err)
+		}
+	}
+}
+
+// SendStream streams the request from the wrapped client, retrying transient failures
+// as long as no text has been passed to onChunk. Once text has been passed on, a
+// failure is returned as is, as the caller may already have used the partial text.
+// Parameters:
+//   - ctx: The context of the request. Retrying stops when it is done.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order.
+//
+// Returns:
+//   - *Response: The whole generated content.
+//   - error: The last error if all attempts fail, or the first error that cannot be retried.
+func (r *RetryClient) SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error) {
+	tracker := &chunkTracker{onChunk: onChunk}
+	for attempt := 0; ; attempt++ {
+		resp, err := SendStream(ctx, r.next, req, tracker.handle)
+		if err == nil {
+			return resp, nil
+		}
+		if tracker.emitted || !IsRetryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+		if attempt >= r.policy.MaxRetries {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		if sleepErr := r.sleep(ctx, r.backoff(attempt, err)); sleepErr != nil {
+			return nil, fmt.Errorf("retry aborted: %w (last error: %w)", sleepErr, err)
+		}
+	}
+}
+
+// backoff returns the delay before the retry following the given attempt.
+// The server's retry delay is used if err carries one; otherwise the delay is
+// drawn uniformly from [0, min(MaxBackoff, InitialBackoff*2^attempt)].
+func (r *RetryClient) backoff(attempt int, err error) time.Duration {
+	if d := retryAfter(err); d > 0 {
+		return d
+	}
+
+	ceiling := r.policy.MaxBackoff
+	// Beyond 30 doublings the shift could overflow; the cap applies long before that anyway.
+	if attempt < 30 {
+		if d := r.policy.InitialBackoff << attempt; d < ceiling {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling + 1)
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+// It returns the context error if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
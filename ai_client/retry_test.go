@@ -0,0 +1,126 @@
+package aiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sequenceClient returns the given errors in order, then a response with the text.
+type sequenceClient struct {
+	errs  []error
+	text  string
+	calls int
+}
+
+func (c *sequenceClient) Send(_ context.Context, _ Request) (*Response, error) {
+	c.calls++
+	if c.calls <= len(c.errs) {
+		return nil, c.errs[c.calls-1]
+	}
+	return &Response{Text: c.text, Backend: "test:model"}, nil
+}
+
+func newTestRetryClient(next GenAIClient, maxRetries int) (*RetryClient, *[]time.Duration) {
+	var delays []time.Duration
+	client := NewRetryClient(next, RetryPolicy{
+		MaxRetries:     maxRetries,
+		InitialBackoff: time.Second,
+		MaxBackoff:     4 * time.Second,
+	})
+	client.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return client, &delays
+}
+
+func TestRetryClient_Send(t *testing.T) {
+	unavailable := &APIError{Backend: "test", StatusCode: http.StatusServiceUnavailable}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "transient errors then success",
+			errs:      []error{unavailable, fmt.Errorf("wrapped: %w", ErrEmptyResponse), context.DeadlineExceeded},
+			wantCalls: 4,
+		},
+		{
+			name:      "permanent error fails fast",
+			errs:      []error{&APIError{Backend: "test", StatusCode: http.StatusUnauthorized}},
+			wantCalls: 1,
+			wantErr:   ErrUnauthorized,
+		},
+		{
+			name:      "unknown error is not retried",
+			errs:      []error{errors.New("boom")},
+			wantCalls: 1,
+		},
+		{
+			name:      "gives up after max retries",
+			errs:      []error{unavailable, unavailable, unavailable, unavailable, unavailable},
+			wantCalls: 4,
+			wantErr:   ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &sequenceClient{errs: tt.errs, text: "ok"}
+			client, delays := newTestRetryClient(next, 3)
+
+			resp, err := client.Send(context.Background(), Request{})
+			assert.Equal(t, tt.wantCalls, next.calls)
+			assert.Len(t, *delays, tt.wantCalls-1)
+			if tt.wantCalls == len(tt.errs)+1 {
+				assert.NoError(t, err)
+				assert.Equal(t, "ok", resp.Text)
+				return
+			}
+			assert.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryClient_Backoff(t *testing.T) {
+	next := &sequenceClient{errs: []error{
+		&APIError{StatusCode: http.StatusTooManyRequests},
+		&APIError{StatusCode: http.StatusTooManyRequests},
+		&APIError{StatusCode: http.StatusTooManyRequests},
+		&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second},
+	}, text: "ok"}
+	client, delays := newTestRetryClient(next, 5)
+
+	_, err := client.Send(context.Background(), Request{})
+	assert.NoError(t, err)
+	assert.Len(t, *delays, 4)
+	for i, ceiling := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		assert.GreaterOrEqual(t, (*delays)[i], time.Duration(0))
+		assert.LessOrEqual(t, (*delays)[i], ceiling, "delay %d exceeds its ceiling", i)
+	}
+	assert.Equal(t, 30*time.Second, (*delays)[3], "the server's retry delay should be honoured")
+}
+
+func TestRetryClient_Send_ContextCancelled(t *testing.T) {
+	next := &sequenceClient{errs: []error{&APIError{StatusCode: http.StatusServiceUnavailable}}, text: "ok"}
+	client := NewRetryClient(next, RetryPolicy{MaxRetries: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Send(ctx, Request{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 1, next.calls)
+}
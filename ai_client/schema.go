@@ -0,0 +1,211 @@
+package aiclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"google.golang.org/genai"
+)
+
+// Schema types supported by every backend.
+const (
+	SchemaTypeObject  = "object"
+	SchemaTypeArray   = "array"
+	SchemaTypeString  = "string"
+	SchemaTypeNumber  = "number"
+	SchemaTypeInteger = "integer"
+	SchemaTypeBoolean = "boolean"
+)
+
+// schemaWrapperProperty is the property that holds a non-object response
+// for backends that only accept an object at the root of a schema.
+const schemaWrapperProperty = "result"
+
+// Schema describes the JSON structure a response must follow.
+// It is the subset of JSON Schema that Gemini, OpenAI compatible servers,
+// Anthropic and Ollama can all enforce natively, and it can be loaded from a JSON Schema file.
+type Schema struct {
+	// Type is one of the SchemaType constants.
+	Type string `json:"type"`
+
+	// Description explains the meaning of the value to the model.
+	Description string `json:"description,omitempty"`
+
+	// Properties holds the fields of an object.
+	Properties map[string]*Schema `json:"properties,omitempty"`
+
+	// Required lists the properties that must be present. Its order is also
+	// used as the property order where a backend supports one.
+	Required []string `json:"required,omitempty"`
+
+	// Items is the schema of the elements of an array.
+	Items *Schema `json:"items,omitempty"`
+
+	// Enum restricts a string to the listed values.
+	Enum []string `json:"enum,omitempty"`
+}
+
+// ParseSchema parses and validates a JSON Schema document.
+// Parameters:
+//   - data: The JSON Schema document.
+//
+// Returns:
+//   - *Schema: The parsed schema.
+//   - error: An error if the document is not valid JSON or uses unsupported types.
+func ParseSchema(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// validate checks that the schema and all nested schemas use supported types.
+func (s *Schema) validate() error {
+	switch s.Type {
+	case SchemaTypeObject:
+		for name, prop := range s.Properties {
+			if prop == nil {
+				return fmt.Errorf("property %q has no schema", name)
+			}
+			if err := prop.validate(); err != nil {
+				return fmt.Errorf("property %q: %w", name, err)
+			}
+		}
+	case SchemaTypeArray:
+		if s.Items == nil {
+			return fmt.Errorf("array schema has no items")
+		}
+		return s.Items.validate()
+	case SchemaTypeString, SchemaTypeNumber, SchemaTypeInteger, SchemaTypeBoolean:
+	default:
+		return fmt.Errorf("unsupported schema type %q", s.Type)
+	}
+	return nil
+}
+
+// propertyNames returns the property names of an object schema,
+// the required ones first in their declared order, followed by the rest sorted by name.
+func (s *Schema) propertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	seen := make(map[string]bool, len(s.Properties))
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; ok && !seen[name] {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	var rest []string
+	for name := range s.Properties {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// geminiSchema converts the schema to a genai schema.
+func (s *Schema) geminiSchema() *genai.Schema {
+	gs := &genai.Schema{
+		Description: s.Description,
+		Required:    s.Required,
+		Enum:        s.Enum,
+	}
+	switch s.Type {
+	case SchemaTypeObject:
+		gs.Type = genai.TypeObject
+		gs.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			gs.Properties[name] = prop.geminiSchema()
+		}
+		gs.PropertyOrdering = s.propertyNames()
+	case SchemaTypeArray:
+		gs.Type = genai.TypeArray
+		gs.Items = s.Items.geminiSchema()
+	case SchemaTypeString:
+		gs.Type = genai.TypeString
+	case SchemaTypeNumber:
+		gs.Type = genai.TypeNumber
+	case SchemaTypeInteger:
+		gs.Type = genai.TypeInteger
+	case SchemaTypeBoolean:
+		gs.Type = genai.TypeBoolean
+	}
+	return gs
+}
+
+// strictJSONSchema converts the schema to a JSON Schema in the form required by
+// OpenAI strict structured outputs: every object lists all its properties as
+// required and forbids additional properties.
+func (s *Schema) strictJSONSchema() map[string]any {
+	js := map[string]any{"type": s.Type}
+	if s.Description != "" {
+		js["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		js["enum"] = s.Enum
+	}
+	switch s.Type {
+	case SchemaTypeObject:
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = prop.strictJSONSchema()
+		}
+		js["properties"] = props
+		js["required"] = s.propertyNames()
+		js["additionalProperties"] = false
+	case SchemaTypeArray:
+		js["items"] = s.Items.strictJSONSchema()
+	}
+	return js
+}
+
+// promptInstruction describes the schema in words for backends that cannot enforce it,
+// so that the model is at least asked for the right structure.
+func (s *Schema) promptInstruction() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return "Respond only with JSON that follows this JSON schema, without any other text:\n" + string(data)
+}
+
+// objectRoot returns a schema with an object at the root, as required by the
+// OpenAI and Anthropic APIs. A non-object schema is wrapped in the property
+// named by schemaWrapperProperty; the reported bool is true in that case and the
+// response has to be unwrapped with unwrapSchemaResult.
+func (s *Schema) objectRoot() (*Schema, bool) {
+	if s.Type == SchemaTypeObject {
+		return s, false
+	}
+	return &Schema{
+		Type:       SchemaTypeObject,
+		Properties: map[string]*Schema{schemaWrapperProperty: s},
+		Required:   []string{schemaWrapperProperty},
+	}, true
+}
+
+// unwrapSchemaResult extracts the value of the wrapper property from a response
+// produced with a schema wrapped by objectRoot.
+// Parameters:
+//   - text: The JSON response.
+//
+// Returns:
+//   - string: The JSON of the wrapped value.
+//   - error: An error if the response is not an object holding the wrapper property.
+func unwrapSchemaResult(text string) (string, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(text), &wrapper); err != nil {
+		return "", fmt.Errorf("failed to parse structured response: %w: %w", ErrMalformedOutput, err)
+	}
+	result, ok := wrapper[schemaWrapperProperty]
+	if !ok {
+		return "", fmt.Errorf("structured response has no %q property: %w", schemaWrapperProperty, ErrMalformedOutput)
+	}
+	return string(result), nil
+}
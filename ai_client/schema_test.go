@@ -0,0 +1,98 @@
+package aiclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+var testSummarySchema = &Schema{
+	Type: SchemaTypeArray,
+	Items: &Schema{
+		Type: SchemaTypeObject,
+		Properties: map[string]*Schema{
+			"summary": {Type: SchemaTypeString},
+			"heading": {Type: SchemaTypeString},
+		},
+		Required: []string{"heading", "summary"},
+	},
+}
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:  "array of objects",
+			input: `{"type":"array","items":{"type":"object","properties":{"heading":{"type":"string"}},"required":["heading"]}}`,
+		},
+		{
+			name:    "invalid json",
+			input:   `{"type":`,
+			wantErr: true,
+		},
+		{
+			name:    "array without items",
+			input:   `{"type":"array"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			input:   `{"type":"object","properties":{"n":{"type":"null"}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSchema([]byte(tt.input))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, SchemaTypeArray, schema.Type)
+			assert.Equal(t, SchemaTypeString, schema.Items.Properties["heading"].Type)
+		})
+	}
+}
+
+func TestSchema_geminiSchema(t *testing.T) {
+	gs := testSummarySchema.geminiSchema()
+
+	assert.Equal(t, genai.TypeArray, gs.Type)
+	assert.Equal(t, genai.TypeObject, gs.Items.Type)
+	assert.Equal(t, genai.TypeString, gs.Items.Properties["heading"].Type)
+	assert.Equal(t, []string{"heading", "summary"}, gs.Items.Required)
+	assert.Equal(t, []string{"heading", "summary"}, gs.Items.PropertyOrdering)
+}
+
+func TestSchema_strictJSONSchema(t *testing.T) {
+	js := testSummarySchema.strictJSONSchema()
+
+	items := js["items"].(map[string]any)
+	assert.Equal(t, "object", items["type"])
+	assert.Equal(t, false, items["additionalProperties"])
+	assert.Equal(t, []string{"heading", "summary"}, items["required"])
+}
+
+func TestSchema_objectRoot(t *testing.T) {
+	root, wrapped := testSummarySchema.objectRoot()
+	assert.True(t, wrapped)
+	assert.Equal(t, SchemaTypeObject, root.Type)
+	assert.Same(t, testSummarySchema, root.Properties[schemaWrapperProperty])
+
+	root, wrapped = testSummarySchema.Items.objectRoot()
+	assert.False(t, wrapped)
+	assert.Same(t, testSummarySchema.Items, root)
+
+	result, err := unwrapSchemaResult(`{"result":[{"heading":"h","summary":"s"}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"heading":"h","summary":"s"}]`, result)
+
+	_, err = unwrapSchemaResult(`{"other":[]}`)
+	assert.Error(t, err)
+}
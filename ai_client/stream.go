@@ -0,0 +1,61 @@
+package aiclient
+
+import "context"
+
+// StreamingClient is a GenAIClient that can deliver generated text as it arrives.
+type StreamingClient interface {
+	GenAIClient
+
+	// SendStream sends a generation request and passes the text to onChunk as it is generated.
+	// Parameters:
+	//   - ctx: The context of the request. Cancelling it aborts the generation.
+	//   - req: The system instruction, conversation and generation options.
+	//   - onChunk: Called with each piece of text in order. Returning an error aborts the generation.
+	// Returns:
+	//   - *Response: The whole generated content, assembled from the chunks, and its usage.
+	//   - error: An error if the generation fails or onChunk returns an error.
+	SendStream(ctx context.Context, req Request, onChunk func(string) error) (*Response, error)
+}
+
+// SendStream streams a request if client implements StreamingClient. Otherwise it
+// sends the request with Send and passes the whole text to onChunk at once.
+// Parameters:
+//   - ctx: The context of the request.
+//   - client: The client to send the request with.
+//   - req: The request to send.
+//   - onChunk: Called with each piece of text in order. Returning an error aborts the generation.
+//
+// Returns:
+//   - *Response: The whole generated content and its usage.
+//   - error: An error if the generation fails or onChunk returns an error.
+func SendStream(ctx context.Context, client GenAIClient, req Request, onChunk func(string) error) (*Response, error) {
+	if streaming, ok := client.(StreamingClient); ok {
+		return streaming.SendStream(ctx, req, onChunk)
+	}
+	resp, err := client.Send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := onChunk(resp.Text); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// chunkTracker wraps an onChunk callback and records whether any text was passed on,
+// after which a failed request can no longer be retried transparently.
+type chunkTracker struct {
+	// onChunk is the wrapped callback.
+	onChunk func(string) error
+
+	// emitted is true once a non-empty chunk was passed on.
+	emitted bool
+}
+
+// handle passes chunk on to the wrapped callback.
+func (t *chunkTracker) handle(chunk string) error {
+	if chunk != "" {
+		t.emitted = true
+	}
+	return t.onChunk(chunk)
+}
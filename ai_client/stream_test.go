@@ -0,0 +1,156 @@
+package aiclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+// streamingClient streams chunks and then fails with err, if set. Its first
+// len(errs) calls fail before streaming anything.
+type streamingClient struct {
+	chunks []string
+	errs   []error
+	err    error
+	calls  int
+}
+
+func (c *streamingClient) Send(ctx context.Context, req Request) (*Response, error) {
+	return c.SendStream(ctx, req, func(string) error { return nil })
+}
+
+func (c *streamingClient) SendStream(_ context.Context, _ Request, onChunk func(string) error) (*Response, error) {
+	c.calls++
+	if c.calls <= len(c.errs) {
+		return nil, c.errs[c.calls-1]
+	}
+	for _, chunk := range c.chunks {
+		if err := onChunk(chunk); err != nil {
+			return nil, err
+		}
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &Response{Text: strings.Join(c.chunks, ""), Backend: "stream:model"}, nil
+}
+
+// collect returns an onChunk callback appending to chunks.
+func collect(chunks *[]string) func(string) error {
+	return func(chunk string) error {
+		*chunks = append(*chunks, chunk)
+		return nil
+	}
+}
+
+func TestSendStream_NotStreaming(t *testing.T) {
+	var chunks []string
+	resp, err := SendStream(context.Background(), &sequenceClient{text: "whole"}, Request{}, collect(&chunks))
+	assert.NoError(t, err)
+	assert.Equal(t, "whole", resp.Text)
+	assert.Equal(t, []string{"whole"}, chunks)
+}
+
+func TestGeminiClient_SendStream(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/test-model:streamGenerateContent", r.URL.Path)
+		assert.Equal(t, "sse", r.URL.Query().Get("alt"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello, "}]}}],"usageMetadata":{"promptTokenCount":10}}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"world"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":10,"candidatesTokenCount":3}}`,
+		} {
+			_, err := fmt.Fprintf(w, "data: %s\n\n", event)
+			assert.NoError(t, err)
+			w.(http.Flusher).Flush()
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	client, err := NewGeminiClient(context.Background(), "test-model", &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: ts.URL},
+	})
+	assert.NoError(t, err)
+
+	var chunks []string
+	resp, err := client.SendStream(context.Background(), NewUserRequest("", "hello", GenerationOptions{}), collect(&chunks))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Hello, ", "world"}, chunks)
+	assert.Equal(t, "Hello, world", resp.Text)
+	assert.Equal(t, Usage{PromptTokens: 10, OutputTokens: 3}, resp.Usage)
+}
+
+func TestRetryClient_SendStream(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+
+	next := &streamingClient{chunks: []string{"a", "b"}, errs: []error{unavailable}}
+	client, _ := newTestRetryClient(next, 3)
+	var chunks []string
+	resp, err := client.SendStream(context.Background(), Request{}, collect(&chunks))
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", resp.Text)
+	assert.Equal(t, []string{"a", "b"}, chunks, "a failure before streaming should be retried transparently")
+	assert.Equal(t, 2, next.calls)
+
+	next = &streamingClient{chunks: []string{"a"}, err: unavailable}
+	client, _ = newTestRetryClient(next, 3)
+	chunks = nil
+	_, err = client.SendStream(context.Background(), Request{}, collect(&chunks))
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, []string{"a"}, chunks)
+	assert.Equal(t, 1, next.calls, "a failure after text was streamed must not be retried")
+}
+
+func TestFallbackClient_SendStream(t *testing.T) {
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+
+	second := &streamingClient{chunks: []string{"local"}}
+	client, err := NewFallbackClient(
+		FallbackEntry{Name: "first", Client: &streamingClient{errs: []error{unavailable}}},
+		FallbackEntry{Name: "second", Client: second},
+	)
+	assert.NoError(t, err)
+	var chunks []string
+	resp, err := client.SendStream(context.Background(), Request{}, collect(&chunks))
+	assert.NoError(t, err)
+	assert.Equal(t, "local", resp.Text)
+	assert.Equal(t, []string{"local"}, chunks)
+
+	second.calls = 0
+	client, err = NewFallbackClient(
+		FallbackEntry{Name: "first", Client: &streamingClient{chunks: []string{"partial"}, err: unavailable}},
+		FallbackEntry{Name: "second", Client: second},
+	)
+	assert.NoError(t, err)
+	_, err = client.SendStream(context.Background(), Request{}, func(string) error { return nil })
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, 0, second.calls, "no fallback once text was streamed")
+}
+
+func TestCachingClient_SendStream(t *testing.T) {
+	next := &streamingClient{chunks: []string{"a", "b"}}
+	client := NewCachingClient(next, t.TempDir(), "stream:model", time.Hour)
+
+	var chunks []string
+	_, err := client.SendStream(context.Background(), Request{}, collect(&chunks))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, chunks)
+
+	chunks = nil
+	resp, err := client.SendStream(context.Background(), Request{}, collect(&chunks))
+	assert.NoError(t, err)
+	assert.Equal(t, "ab", resp.Text)
+	assert.Equal(t, []string{"ab"}, chunks, "a cached response should be passed on at once")
+	assert.Equal(t, 1, next.calls)
+}
@@ -2,7 +2,14 @@
 package cmd
 
 import (
+	"context"
+	genAi "feed-summarizer/ai_client"
+	"feed-summarizer/fetcher"
+	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -19,10 +26,81 @@ var (
 	formatOutput bool
 	// outputTemplatePath is the path to custom output template file
 	outputTemplatePath string
+	// structuredOutput determines whether the model is asked for JSON following a response schema
+	structuredOutput bool
+	// pageContent selects whether pages are given to the model as raw HTML, plain text or the main article
+	pageContent string
+	// attachImages determines whether the lead image of each feed item is attached to the request
+	attachImages bool
+	// responseSchemaPath is the path to a custom JSON schema of the response
+	responseSchemaPath string
 	// outputDest specifies where to send the output (standard, file, or datastore)
 	outputDest string
 
+	// gcpProjectID is the GCP project used by Datastore and Vertex AI
 	gcpProjectID string
+	// gcpLocation is the GCP region of Vertex AI, which is selected for Gemini by gcpProjectID
+	gcpLocation string
+	// geminiBaseURL points the Gemini client at an alternate endpoint
+	geminiBaseURL string
+
+	// model overrides the default model of the selected API
+	model string
+	// temperature controls the randomness of the output
+	temperature float32
+	// maxOutputTokens limits the number of generated tokens
+	maxOutputTokens int32
+	// topP is the nucleus sampling cutoff
+	topP float32
+	// seed makes sampling reproducible on backends that support it
+	seed int32
+	// stopSequences stop generation when any of them is produced
+	stopSequences []string
+
+	// fallbacks lists the backends tried in order when the primary one fails, as "kind[:model]"
+	fallbacks []string
+	// maxRetries is the number of retries of transient AI API failures
+	maxRetries int
+	// maxContinuations is the number of follow-up requests made to complete a truncated response
+	maxContinuations int
+	// requestsPerMinute limits the AI API requests per minute of the primary provider and model
+	requestsPerMinute int
+	// tokensPerMinute limits the estimated AI API tokens per minute of the primary provider and model
+	tokensPerMinute int
+	// rateLimits are the budgets of individual backends, as "kind[:model]=rpm/tpm"
+	rateLimits []string
+
+	// noCache disables the on-disk response cache
+	noCache bool
+	// cacheDir is the directory of the on-disk response cache
+	cacheDir string
+	// cacheTTL is how long cached responses are served
+	cacheTTL time.Duration
+	// noHTTPCache disables the conditional GET cache of feeds and pages
+	noHTTPCache bool
+	// userAgent is the User-Agent of the requests for feeds, pages, images and robots.txt
+	userAgent string
+	// maxPerHost limits the concurrent page requests per host
+	maxPerHost int
+	// hostDelay is the minimum time between two page requests to the same host
+	hostDelay time.Duration
+
+	// cassettePath is the fixture file AI requests and responses are recorded to or replayed from
+	cassettePath string
+	// cassetteMode selects whether the cassette is recorded or replayed
+	cassetteMode string
+
+	// priceTablePath is the path to a JSON price table used to estimate costs
+	priceTablePath string
+	// usageReportPath is the path the token usage and cost report of the run is written to
+	usageReportPath string
+
+	// batchMode submits all feeds as a single batch job instead of a request per feed
+	batchMode bool
+	// batchStatePath is the file the ID of a running batch job is saved to for resuming
+	batchStatePath string
+	// batchPollInterval is the time between polls of the batch job status
+	batchPollInterval time.Duration
 )
 
 // rootCmd is the base command for feed summarizer CLI.
@@ -41,18 +119,64 @@ Example:
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// The command context is cancelled on interrupt, which aborts in-flight requests.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		stop()
 		os.Exit(1)
 	}
 }
 
 func init() {
-	rootCmd.Flags().StringVar(&genAPIKind, "gen-api-kind", "gemini", "Generative AI API type (currently only 'gemini' is supported)")
+	rootCmd.Flags().StringVar(&genAPIKind, "gen-api-kind", "gemini", "Generative AI API type ('gemini', 'openai', 'anthropic', 'ollama' or 'extractive' for offline extraction without a model)")
 	rootCmd.Flags().StringVar(&systemPromptPath, "system-prompt", "", "Path to custom system prompt template file")
 	rootCmd.Flags().StringVar(&userPromptPath, "user-prompt", "", "Path to custom user prompt template file")
 	rootCmd.Flags().BoolVar(&formatOutput, "format", false, "Format output as JSON with template")
 	rootCmd.Flags().StringVar(&outputTemplatePath, "output-template", "", "Custom output template path (only used when -format is true)")
+	rootCmd.Flags().StringVar(&pageContent, "page-content", string(fetcher.PageContentRaw), "How fetched pages are given to the model ('raw' HTML, plain 'text', the main 'article' with its title, author and date, or 'markdown')")
+	rootCmd.Flags().BoolVar(&attachImages, "attach-images", false, fmt.Sprintf("Attach the lead image of each feed item, downscaled to %dpx, for models that support vision", fetcher.MaxImageDimension))
+	rootCmd.Flags().BoolVar(&structuredOutput, "structured-output", false, "Request JSON output following the response schema from the model; implied by --response-schema")
+	rootCmd.Flags().StringVar(&responseSchemaPath, "response-schema", "", "Path to a custom JSON schema of the response (default: array of {heading, summary})")
 	rootCmd.Flags().StringVar(&outputDest, "output-dest", "standard", "Output destination (e.g., 'standard', 'file', 'datastore')")
-	rootCmd.Flags().StringVar(&gcpProjectID, "gcp-project-id", "", "GCP project ID (required for datastore)")
+	rootCmd.Flags().StringVar(&gcpProjectID, "gcp-project-id", "", "GCP project ID (required for datastore; selects Vertex AI instead of GEMINI_API_KEY for Gemini)")
+	rootCmd.Flags().StringVar(&gcpLocation, "gcp-location", "", "GCP location of Vertex AI (default 'us-central1')")
+	rootCmd.Flags().StringVar(&geminiBaseURL, "gemini-base-url", "", "Alternate Gemini API endpoint, e.g. a gateway or a local fake server")
+	rootCmd.Flags().StringVar(&model, "model", "", "Model name (defaults to the selected API's default model)")
+	rootCmd.Flags().Float32Var(&temperature, "temperature", 0, "Sampling temperature (backend default if not set)")
+	rootCmd.Flags().Int32Var(&maxOutputTokens, "max-output-tokens", 0, "Maximum number of tokens to generate (backend default if not set)")
+	rootCmd.Flags().Float32Var(&topP, "top-p", 0, "Nucleus sampling cutoff (backend default if not set)")
+	rootCmd.Flags().Int32Var(&seed, "seed", 0, "Sampling seed for reproducible output (backend default if not set)")
+	rootCmd.Flags().StringSliceVar(&stopSequences, "stop", nil, "Stop sequences; can be repeated or comma separated")
+	rootCmd.Flags().StringSliceVar(&fallbacks, "fallback", nil, "Backends tried in order when the primary one is rate limited, unavailable, blocked, times out or returns malformed output, as 'kind[:model]', e.g. 'gemini:gemini-2.5-flash,ollama:llama3.2'")
+	rootCmd.Flags().IntVar(&maxContinuations, "max-continuations", 2, "Number of follow-up requests made to complete a response truncated at the output token limit (0 disables continuing)")
+	rootCmd.Flags().IntVar(&maxRetries, "max-retries", genAi.DefaultRetryPolicy.MaxRetries, "Number of retries of transient AI API failures such as rate limiting or 5xx errors (0 disables retrying)")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Always call the AI API instead of serving repeated requests from the response cache")
+	rootCmd.Flags().StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "Directory of the AI response cache")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", 24*time.Hour, "How long cached AI responses are served")
+	rootCmd.Flags().BoolVar(&noHTTPCache, "no-http-cache", false, "Always download feeds and pages instead of revalidating the copies cached in the cache directory")
+	rootCmd.Flags().StringVar(&userAgent, "user-agent", fetcher.DefaultUserAgent, "User-Agent of the requests for feeds, pages and images; its product token selects the robots.txt rules")
+	rootCmd.Flags().IntVar(&maxPerHost, "max-per-host", 2, "Maximum concurrent page requests per host")
+	rootCmd.Flags().DurationVar(&hostDelay, "host-delay", time.Second, "Minimum time between page requests to the same host; a longer robots.txt Crawl-delay takes precedence")
+	rootCmd.Flags().StringVar(&cassettePath, "cassette", "", "Fixture file to record AI requests and responses to, or replay them from offline")
+	rootCmd.Flags().StringVar(&cassetteMode, "cassette-mode", string(genAi.CassetteReplay), "Cassette mode ('record' or 'replay')")
+	rootCmd.Flags().StringVar(&priceTablePath, "price-table", "", "Path to a JSON price table in USD per million tokens per backend, e.g. templates/price_table.json")
+	rootCmd.Flags().StringVar(&usageReportPath, "usage-report", "", "Path to write the token usage and cost estimate of the run as JSON")
+	rootCmd.Flags().IntVar(&requestsPerMinute, "rpm", 0, "Maximum AI API requests per minute of the primary backend; requests over budget wait (0 means unlimited)")
+	rootCmd.Flags().IntVar(&tokensPerMinute, "tpm", 0, "Maximum estimated AI API tokens per minute of the primary backend; requests over budget wait (0 means unlimited)")
+	rootCmd.Flags().StringSliceVar(&rateLimits, "rate-limit", nil, "Budget of a single backend as 'kind[:model]=rpm/tpm', e.g. 'openai:gpt-4o-mini=500/200000' or 'ollama=/50000'; can be repeated, and takes precedence over --rpm and --tpm")
+	rootCmd.Flags().BoolVar(&batchMode, "batch", false, "Submit all feeds as one batch job at the discounted batch price and wait for it ('gemini' and 'openai' only)")
+	rootCmd.Flags().StringVar(&batchStatePath, "batch-state", "", "File the running batch job is saved to, so an interrupted run resumes it (default: batch-state.json in the cache directory)")
+	rootCmd.Flags().DurationVar(&batchPollInterval, "batch-poll-interval", time.Minute, "Time between polls of the batch job status")
+}
+
+// defaultCacheDir returns the response cache directory under the user's cache directory,
+// or a directory relative to the working directory if there is none.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".feed-summarizer-cache"
+	}
+	return filepath.Join(dir, "feed-summarizer")
 }
@@ -2,74 +2,408 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	genAi "feed-summarizer/ai_client"
 	db "feed-summarizer/database"
 	"feed-summarizer/fetcher"
 	"feed-summarizer/jsonify"
 	sum "feed-summarizer/summarize"
+	"feed-summarizer/usage"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/spf13/cobra"
 )
 
-func summarize(_ *cobra.Command, args []string) error {
-	sumClient := genAi.NewGenAIClient(genAPIKind)
-	if sumClient == nil {
-		return fmt.Errorf("unsupported API type: %s", genAPIKind)
+func summarize(cmd *cobra.Command, args []string) (err error) {
+	ctx := cmd.Context()
+	var prices usage.PriceTable
+	if priceTablePath != "" {
+		if prices, err = usage.LoadPriceTable(priceTablePath); err != nil {
+			return err
+		}
+	}
+	tracker := usage.NewTracker(prices)
+	sumClient, err := newGenAIClient(ctx, tracker.Record)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		err = errors.Join(err, reportUsage(tracker.Report()))
+	}()
 
-	summarizer := sum.NewSummarizer(sumClient, fetcher.FetchFeed, fetcher.FetchHTML)
+	feedFetcher, pageFetcher := fetcher.NewFeedFetcher(userAgent), fetcher.NewHTMLPageFetcher(userAgent)
+	if !noHTTPCache {
+		// Unchanged feeds and pages are revalidated with conditional requests instead of downloaded again.
+		httpCache := fetcher.NewHTTPCache(filepath.Join(cacheDir, "http"), userAgent)
+		feedFetcher, pageFetcher = httpCache.FetchFeed, httpCache.FetchHTML
+	}
+	// Pages are fetched within per-host limits and only if robots.txt allows it.
+	pageFetcher = fetcher.NewPoliteness(maxPerHost, hostDelay, userAgent).Wrap(pageFetcher)
+	summarizer := sum.NewSummarizer(sumClient, feedFetcher, pageFetcher)
+	summarizer.SetGenerationOptions(generationOptions(cmd))
+	mode, err := fetcher.ParsePageContent(pageContent)
+	if err != nil {
+		return err
+	}
+	summarizer.SetPageContent(mode)
+	if attachImages {
+		summarizer.SetImageFetcher(fetcher.NewImageFetcher(userAgent))
+	}
+	switch {
+	case responseSchemaPath != "":
+		data, err := os.ReadFile(responseSchemaPath)
+		if err != nil {
+			return fmt.Errorf("failed to read response schema from %s: %w", responseSchemaPath, err)
+		}
+		schema, err := genAi.ParseSchema(data)
+		if err != nil {
+			return fmt.Errorf("invalid response schema %s: %w", responseSchemaPath, err)
+		}
+		summarizer.SetResponseSchema(schema)
+	case !structuredOutput:
+		summarizer.SetResponseSchema(nil)
+	}
 	if systemPromptPath != "" && userPromptPath != "" {
 		if err := summarizer.LoadPromptBuilder(systemPromptPath, userPromptPath); err != nil {
 			return fmt.Errorf("failed to load prompt builder: %w", err)
 		}
 	}
 
+	if batchMode {
+		return summarizeBatch(ctx, summarizer, args, tracker)
+	}
+
 	for _, url := range args {
-		summary, err := summarizer.Summarize(url)
+		// Unformatted output is printed as it is generated; formatted output needs the whole summary.
+		onChunk := func(string) error { return nil }
+		if !formatOutput {
+			onChunk = func(chunk string) error {
+				_, err := fmt.Print(chunk)
+				return err
+			}
+		}
+		resp, err := summarizer.SummarizeStream(ctx, url, onChunk)
 		if err != nil {
 			return fmt.Errorf("failed to summarize feed: %w", err)
 		}
+		if err := outputSummary(ctx, url, resp, true); err != nil {
+			return err
+		}
+	}
 
-		if !formatOutput {
-			fmt.Println(summary)
-			return nil
+	return nil
+}
+
+// summarizeBatch summarizes the feeds with a single batch job of the selected backend.
+// Feeds that fail are logged and the others are still output.
+// Parameters:
+//   - ctx: The context of the run
+//   - summarizer: The summarizer, whose client summarizes blocked feeds item by item
+//   - feedURLs: The URLs of the RSS feeds
+//   - tracker: The usage tracker of the run
+//
+// Returns:
+//   - error: An error if the job fails, or the errors of the feeds that could not be summarized
+func summarizeBatch(ctx context.Context, summarizer *sum.Summarizer, feedURLs []string, tracker *usage.Tracker) error {
+	if cassettePath != "" {
+		return fmt.Errorf("--batch cannot be combined with --cassette")
+	}
+	batchClient, err := genAi.NewBatchClient(ctx, genAPIKind, genAi.Config{
+		Model:         model,
+		GCPProjectID:  gcpProjectID,
+		GCPLocation:   gcpLocation,
+		GeminiBaseURL: geminiBaseURL,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create batch client: %w", err)
+	}
+	statePath := batchStatePath
+	if statePath == "" {
+		statePath = filepath.Join(cacheDir, "batch-state.json")
+	}
+
+	results, err := summarizer.SummarizeBatch(ctx, batchClient, feedURLs, statePath, batchPollInterval)
+	if err != nil {
+		return fmt.Errorf("failed to summarize feeds in batch: %w", err)
+	}
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			log.Printf("failed to summarize %s: %v", result.FeedURL, result.Err)
+			errs = append(errs, fmt.Errorf("failed to summarize feed %s: %w", result.FeedURL, result.Err))
+			continue
+		}
+		if result.Batched {
+			// The usage of feeds summarized again item by item is recorded by the backend clients.
+			tracker.Add(result.FeedURL, &result.Summary.Response)
+		}
+		if err := outputSummary(ctx, result.FeedURL, result.Summary, false); err != nil {
+			return err
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		var outputTemplate *template.Template
-		if outputTemplatePath != "" {
-			outputTemplate, err = template.ParseFiles(outputTemplatePath)
-			if err != nil {
-				return fmt.Errorf("failed to read output template from %s: %w", outputTemplatePath, err)
-			}
-		} else {
-			outputTemplate = jsonify.OutputTemplate
+// outputSummary logs the backend, blocked and skipped items of a summary and prints it,
+// formatted with the output template or as is, or saves it to the datastore.
+// Parameters:
+//   - ctx: The context of the run
+//   - url: The URL of the summarized feed
+//   - resp: The summary of the feed
+//   - streamed: Whether the unformatted summary was already printed as it was generated
+//
+// Returns:
+//   - error: An error if the summary cannot be formatted or saved
+func outputSummary(ctx context.Context, url string, resp *sum.Summary, streamed bool) error {
+	log.Printf("summarized %s with %s", url, resp.Backend)
+	for _, blocked := range resp.Blocked {
+		reason := string(blocked.Reason)
+		if blocked.Detail != "" {
+			reason += ": " + blocked.Detail
+		}
+		log.Printf("left out %q (%s), the backend stopped with %s", blocked.Title, blocked.Link, reason)
+	}
+	for _, skipped := range resp.Skipped {
+		log.Printf("skipped %q (%s): %s", skipped.Title, skipped.Link, skipped.Reason)
+	}
+	summary := resp.Text
+
+	if !formatOutput {
+		if !streamed {
+			fmt.Print(summary)
+		}
+		fmt.Println()
+		return nil
+	}
+
+	var outputTemplate *template.Template
+	if outputTemplatePath != "" {
+		var err error
+		outputTemplate, err = template.ParseFiles(outputTemplatePath)
+		if err != nil {
+			return fmt.Errorf("failed to read output template from %s: %w", outputTemplatePath, err)
+		}
+	} else {
+		outputTemplate = jsonify.OutputTemplate
+	}
+
+	formattedResults, err := jsonify.ExtractAndFormat(summary, outputTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to format summary: %w", err)
+	}
+	for _, result := range formattedResults {
+		if entity, ok := result.(map[string]any); ok {
+			entity["backend"] = resp.Backend
 		}
+	}
 
-		formattedResults, err := jsonify.ExtractAndFormat(summary, outputTemplate)
+	switch outputDest {
+	case "datastore":
+		client, err := db.NewDatastoreClient(ctx, gcpProjectID)
+		if err != nil {
+			return fmt.Errorf("failed to create datastore client: %w", err)
+		}
+		keys, err := db.GenerateUUIDs(len(formattedResults))
 		if err != nil {
-			return fmt.Errorf("failed to format summary: %w", err)
+			return fmt.Errorf("failed to generate keys: %w", err)
+		}
+		if err := client.PutMulti(ctx, "summaries", keys, formattedResults); err != nil {
+			return fmt.Errorf("failed to save summary to datastore: %w", err)
 		}
+	default:
+		fmt.Println(formattedResults)
+	}
+	return nil
+}
+
+// newGenAIClient creates the AI client selected by the command line flags.
+// Each backend is rate limited and retried on its own: the primary backend with --rpm
+// and --tpm, and any backend with its --rate-limit entry. When fallback backends are
+// given, they are tried in order after the primary one. Unless disabled, responses
+// are cached on disk, keyed by the resolved backends and the request. A cassette records the
+// responses, or replays them without creating any backend.
+// Parameters:
+//   - ctx: The context used to set up the clients
+//   - record: Records the usage of every request sent to a backend, including retries,
+//     continuations and failed fallbacks
+//
+// Returns:
+//   - genAi.GenAIClient: The AI client to summarize with
+//   - error: An error if a backend is invalid or cannot be set up
+func newGenAIClient(ctx context.Context, record genAi.UsageRecorder) (genAi.GenAIClient, error) {
+	mode := genAi.CassetteMode(cassetteMode)
+	if cassettePath != "" && mode == genAi.CassetteReplay {
+		// Replaying needs no backend, so it works offline and without credentials.
+		return genAi.NewCassetteClient(cassettePath, mode, nil)
+	}
+
+	limits, err := parseRateLimits(rateLimits)
+	if err != nil {
+		return nil, err
+	}
+	label := backendLabel(genAPIKind, model)
+	limit, ok := limits[label]
+	if !ok {
+		limit = genAi.RateLimit{RequestsPerMinute: requestsPerMinute, TokensPerMinute: tokensPerMinute}
+	}
+	client, err := newBackendClient(ctx, genAPIKind, model, limit, record)
+	if err != nil {
+		return nil, err
+	}
 
-		switch outputDest {
-		case "datastore":
-			ctx := context.Background()
-			client, err := db.NewDatastoreClient(ctx, gcpProjectID)
+	if len(fallbacks) > 0 {
+		entries := []genAi.FallbackEntry{{Name: label, Client: client}}
+		for _, fallback := range fallbacks {
+			kind, fallbackModel, _ := strings.Cut(fallback, ":")
+			// Fallbacks are unlimited unless they have a budget of their own.
+			fallbackClient, err := newBackendClient(ctx, kind, fallbackModel, limits[backendLabel(kind, fallbackModel)], record)
 			if err != nil {
-				return fmt.Errorf("failed to create datastore client: %w", err)
+				return nil, fmt.Errorf("invalid fallback %q: %w", fallback, err)
 			}
-			keys, err := db.GenerateUUIDs(len(formattedResults))
-			if err != nil {
-				return fmt.Errorf("failed to generate keys: %w", err)
+			entries = append(entries, genAi.FallbackEntry{Name: backendLabel(kind, fallbackModel), Client: fallbackClient})
+		}
+		if client, err = genAi.NewFallbackClient(entries...); err != nil {
+			return nil, err
+		}
+	}
+
+	if !noCache {
+		// Key the cache by the models and endpoints the backends resolved, which the
+		// flags leave to environment variables and defaults when they are empty.
+		client = genAi.NewCachingClient(client, cacheDir, genAi.Describe(client), cacheTTL)
+	}
+	if cassettePath != "" {
+		return genAi.NewCassetteClient(cassettePath, mode, client)
+	}
+	return client, nil
+}
+
+// newBackendClient creates the client of a single backend, wrapped with the usage recorder
+// and the rate limit, retries and continuations of truncated responses given on the command line.
+// The usage recorder wraps the backend itself, so it sees every attempt.
+// Parameters:
+//   - ctx: The context used to set up the client
+//   - kind: The API kind, e.g. "gemini"
+//   - model: The model name, or empty for the API's default model
+//   - limit: The budget of the backend; zero fields are unlimited
+//   - record: Records the usage of every response of the backend
+//
+// Returns:
+//   - genAi.GenAIClient: The wrapped client
+//   - error: An error if the client cannot be created
+func newBackendClient(ctx context.Context, kind, model string, limit genAi.RateLimit, record genAi.UsageRecorder) (genAi.GenAIClient, error) {
+	client, err := genAi.NewGenAIClient(ctx, kind, genAi.Config{
+		Model:         model,
+		GCPProjectID:  gcpProjectID,
+		GCPLocation:   gcpLocation,
+		GeminiBaseURL: geminiBaseURL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+	client = genAi.NewUsageRecordingClient(client, record)
+	if limit.RequestsPerMinute > 0 || limit.TokensPerMinute > 0 {
+		client = genAi.NewRateLimitedClient(client, genAi.SharedRateLimiter(kind, model, limit))
+	}
+	if maxRetries > 0 {
+		policy := genAi.DefaultRetryPolicy
+		policy.MaxRetries = maxRetries
+		client = genAi.NewRetryClient(client, policy)
+	}
+	if maxContinuations > 0 {
+		client = genAi.NewContinuationClient(client, maxContinuations)
+	}
+	return client, nil
+}
+
+// parseRateLimits parses the --rate-limit entries.
+// Parameters:
+//   - entries: Budgets as "kind[:model]=rpm/tpm"; either number may be empty or 0 for unlimited
+//
+// Returns:
+//   - map[string]genAi.RateLimit: The budgets keyed by backendLabel of the kind and model
+//   - error: An error if an entry is malformed
+func parseRateLimits(entries []string) (map[string]genAi.RateLimit, error) {
+	limits := make(map[string]genAi.RateLimit, len(entries))
+	for _, entry := range entries {
+		label, budget, ok := strings.Cut(entry, "=")
+		rpm, tpm, hasSlash := strings.Cut(budget, "/")
+		if !ok || !hasSlash || label == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected kind[:model]=rpm/tpm", entry)
+		}
+		var limit genAi.RateLimit
+		for _, field := range []struct {
+			value string
+			dest  *int
+		}{{rpm, &limit.RequestsPerMinute}, {tpm, &limit.TokensPerMinute}} {
+			if field.value == "" {
+				continue
 			}
-			if err := client.PutMulti(ctx, "summaries", keys, formattedResults); err != nil {
-				return fmt.Errorf("failed to save summary to datastore: %w", err)
+			n, err := strconv.Atoi(field.value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid rate limit %q, expected kind[:model]=rpm/tpm", entry)
 			}
-		default:
-			fmt.Println(formattedResults)
+			*field.dest = n
 		}
+		limits[label] = limit
 	}
+	return limits, nil
+}
 
+// backendLabel names a backend in error messages.
+func backendLabel(kind, model string) string {
+	if model == "" {
+		return kind
+	}
+	return kind + ":" + model
+}
+
+// reportUsage prints the token usage and cost estimate of the run to standard error,
+// and writes it to the usage report file if one was given.
+// Parameters:
+//   - report: The usage of the run
+//
+// Returns:
+//   - error: An error if the report cannot be written
+func reportUsage(report usage.Report) error {
+	if len(report.Feeds) == 0 {
+		return nil
+	}
+	if err := report.WriteText(os.Stderr); err != nil {
+		return err
+	}
+	if usageReportPath != "" {
+		return report.WriteJSON(usageReportPath)
+	}
 	return nil
 }
+
+// generationOptions builds the generation options from the command line flags.
+// Parameters that were not given on the command line are left unset so the backend defaults apply.
+// Parameters:
+//   - cmd: The Cobra command being run
+//
+// Returns:
+//   - genAi.GenerationOptions: The generation options to pass to the AI client
+func generationOptions(cmd *cobra.Command) genAi.GenerationOptions {
+	opts := genAi.GenerationOptions{
+		MaxOutputTokens: maxOutputTokens,
+		StopSequences:   stopSequences,
+	}
+	if cmd.Flags().Changed("temperature") {
+		opts.Temperature = &temperature
+	}
+	if cmd.Flags().Changed("top-p") {
+		opts.TopP = &topP
+	}
+	if cmd.Flags().Changed("seed") {
+		opts.Seed = &seed
+	}
+	return opts
+}
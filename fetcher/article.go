@@ -0,0 +1,310 @@
+package fetcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// PageContent selects how fetched pages are put into the prompt.
+type PageContent string
+
+const (
+	// PageContentRaw keeps the HTML of the page as fetched.
+	PageContentRaw PageContent = "raw"
+
+	// PageContentText reduces the page to its plain text, one block element per line.
+	PageContentText PageContent = "text"
+
+	// PageContentArticle keeps only the title, author, date and text of the main article.
+	PageContentArticle PageContent = "article"
+
+	// PageContentMarkdown converts the page to Markdown, keeping headings, lists, tables, code blocks and links.
+	PageContentMarkdown PageContent = "markdown"
+)
+
+const (
+	// minArticleRunes is the length of text below which an <article> element or a
+	// scored candidate is not trusted to be the main content.
+	minArticleRunes = 140
+
+	// minParagraphRunes is the length of text below which a paragraph does not count
+	// towards the score of its ancestors.
+	minParagraphRunes = 25
+)
+
+var (
+	// unlikelyCandidates matches class names and IDs of boilerplate such as navigation, ads and comments.
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|comment|cookie|disqus|footer|header|menu|modal|nav|popup|promo|related|remark|share|sidebar|social|sponsor|subscribe|\bads?\b|advert`)
+
+	// likelyCandidates matches class names and IDs of the main content, which are kept even if they also match unlikelyCandidates.
+	likelyCandidates = regexp.MustCompile(`(?i)article|body|content|entry|main|post|story|text`)
+)
+
+// Article is the main content of a page with its metadata.
+type Article struct {
+	// Title is the title of the article.
+	Title string `json:"title,omitempty"`
+
+	// Author is the byline of the article. It may be empty.
+	Author string `json:"author,omitempty"`
+
+	// Published is the publication date as given by the page, e.g. "2025-08-12T09:00:00Z". It may be empty.
+	Published string `json:"published,omitempty"`
+
+	// Text is the text of the article, one paragraph per line.
+	Text string `json:"text"`
+}
+
+// String formats the article as plain text: the metadata on labelled lines,
+// followed by a blank line and the text.
+func (a *Article) String() string {
+	var sb strings.Builder
+	for _, field := range []struct{ label, value string }{
+		{"Title", a.Title},
+		{"Author", a.Author},
+		{"Published", a.Published},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", field.label, field.value)
+		}
+	}
+	if sb.Len() > 0 {
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(a.Text)
+	return sb.String()
+}
+
+// ParsePageContent parses the name of a page content mode.
+// Parameters:
+//   - name: "raw", "text", "article" or "markdown".
+//
+// Returns:
+//   - PageContent: The page content mode.
+//   - error: An error if the name is unknown.
+func ParsePageContent(name string) (PageContent, error) {
+	switch mode := PageContent(name); mode {
+	case PageContentRaw, PageContentText, PageContentArticle, PageContentMarkdown:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown page content %q, expected raw, text, article or markdown", name)
+	}
+}
+
+// ConvertPage converts the HTML of a fetched page to the given page content.
+// Parameters:
+//   - page: The HTML of the page. May be empty.
+//   - pageURL: The URL of the page, which relative links are resolved against in Markdown.
+//   - mode: The page content to convert to.
+//
+// Returns:
+//   - string: The converted page, or page itself for PageContentRaw.
+func ConvertPage(page, pageURL string, mode PageContent) string {
+	switch mode {
+	case PageContentText:
+		return HTMLText(page)
+	case PageContentMarkdown:
+		return HTMLMarkdown(page, pageURL)
+	case PageContentArticle:
+		if article := ExtractArticle(page); article != nil {
+			return article.String()
+		}
+		return ""
+	default:
+		return page
+	}
+}
+
+// ExtractArticle finds the main article of an HTML page and drops the boilerplate around
+// it, in the spirit of Readability. The title, author and date are taken from the usual
+// meta tags and markup. The body is the largest <article> element, or else the element
+// whose paragraphs score highest by length and commas, penalized by the share of link text.
+// Scripts, navigation and elements whose class or ID suggests ads, comments or sidebars
+// are removed first. Pages without a distinct article fall back to their whole text.
+// Parameters:
+//   - page: The HTML of the page. May be empty.
+//
+// Returns:
+//   - *Article: The article, or nil if the page is empty or cannot be parsed.
+func ExtractArticle(page string) *Article {
+	if strings.TrimSpace(page) == "" {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return nil
+	}
+
+	article := &Article{
+		Title:     articleTitle(doc),
+		Author:    articleAuthor(doc),
+		Published: articlePublished(doc),
+	}
+
+	doc.Find(nonContentSelector).Remove()
+	doc.Find("*").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) == "body" || goquery.NodeName(s) == "html" {
+			return
+		}
+		names := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if unlikelyCandidates.MatchString(names) && !likelyCandidates.MatchString(names) {
+			s.Remove()
+		}
+	})
+
+	article.Text = blockText(articleBody(doc))
+	// The title usually opens the body as a heading as well. Only a first line equal to the
+	// title is dropped, so that a paragraph starting with the title is kept whole.
+	if first, rest, _ := strings.Cut(article.Text, "\n"); first == article.Title {
+		article.Text = rest
+	}
+	return article
+}
+
+// articleBody selects the element holding the main content of a cleaned document.
+func articleBody(doc *goquery.Document) *goquery.Selection {
+	var best *goquery.Selection
+	bestLength := 0
+	doc.Find("article, [itemprop=articleBody], [role=main], main").Each(func(_ int, s *goquery.Selection) {
+		if length := utf8.RuneCountInString(collapseSpace(s.Text())); length > bestLength {
+			best, bestLength = s, length
+		}
+	})
+	if best != nil && bestLength >= minArticleRunes {
+		return best
+	}
+
+	if candidate := topCandidate(doc); candidate != nil {
+		return candidate
+	}
+	return doc.Find("body")
+}
+
+// topCandidate scores the ancestors of the paragraphs of a document and returns the highest scoring one.
+// Parameters:
+//   - doc: The cleaned document.
+//
+// Returns:
+//   - *goquery.Selection: The element most likely holding the article, or nil if no element has enough text.
+func topCandidate(doc *goquery.Document) *goquery.Selection {
+	type candidate struct {
+		sel   *goquery.Selection
+		score float64
+	}
+	candidates := map[any]*candidate{}
+	var order []any
+	addScore := func(s *goquery.Selection, score float64) {
+		if s.Length() == 0 || goquery.NodeName(s) == "html" {
+			return
+		}
+		key := s.Get(0)
+		c, ok := candidates[key]
+		if !ok {
+			c = &candidate{sel: s, score: classWeight(s)}
+			candidates[key] = c
+			order = append(order, key)
+		}
+		c.score += score
+	}
+
+	doc.Find("p, pre, td, blockquote").Each(func(_ int, p *goquery.Selection) {
+		text := collapseSpace(p.Text())
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphRunes {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "、")+strings.Count(text, "，"))
+		score += min(float64(length)/100, 3)
+		addScore(p.Parent(), score)
+		addScore(p.Parent().Parent(), score/2)
+	})
+
+	var best *candidate
+	for _, key := range order {
+		c := candidates[key]
+		c.score *= 1 - linkDensity(c.sel)
+		if best == nil || c.score > best.score {
+			best = c
+		}
+	}
+	if best == nil || utf8.RuneCountInString(collapseSpace(best.sel.Text())) < minArticleRunes {
+		return nil
+	}
+	return best.sel
+}
+
+// classWeight is the initial score of a candidate from its class name and ID.
+func classWeight(s *goquery.Selection) float64 {
+	names := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+	weight := 0.0
+	if likelyCandidates.MatchString(names) {
+		weight += 25
+	}
+	if unlikelyCandidates.MatchString(names) {
+		weight -= 25
+	}
+	return weight
+}
+
+// linkDensity is the share of the text of an element that is link text.
+func linkDensity(s *goquery.Selection) float64 {
+	length := utf8.RuneCountInString(collapseSpace(s.Text()))
+	if length == 0 {
+		return 0
+	}
+	links := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		links += utf8.RuneCountInString(collapseSpace(a.Text()))
+	})
+	return float64(links) / float64(length)
+}
+
+// articleTitle returns the title of the article from its Open Graph title, first heading or <title>.
+func articleTitle(doc *goquery.Document) string {
+	return firstNonEmpty(
+		metaContent(doc, `meta[property="og:title"]`, `meta[name="twitter:title"]`),
+		collapseSpace(doc.Find("article h1, h1").First().Text()),
+		collapseSpace(doc.Find("title").First().Text()),
+	)
+}
+
+// articleAuthor returns the byline of the article from its meta tags or author markup.
+func articleAuthor(doc *goquery.Document) string {
+	return firstNonEmpty(
+		metaContent(doc, `meta[name="author"]`, `meta[property="article:author"]`, `meta[name="twitter:creator"]`),
+		collapseSpace(doc.Find(`[itemprop="author"] [itemprop="name"], [itemprop="author"], [rel="author"], .byline, .author`).First().Text()),
+	)
+}
+
+// articlePublished returns the publication date of the article from its meta tags or <time> element.
+func articlePublished(doc *goquery.Document) string {
+	return firstNonEmpty(
+		metaContent(doc, `meta[property="article:published_time"]`, `meta[itemprop="datePublished"]`, `meta[name="date"]`, `meta[name="pubdate"]`),
+		doc.Find(`[itemprop="datePublished"]`).First().AttrOr("datetime", ""),
+		doc.Find("time[datetime]").First().AttrOr("datetime", ""),
+	)
+}
+
+// metaContent returns the content of the first of the selected meta tags that has one.
+func metaContent(doc *goquery.Document, selectors ...string) string {
+	for _, selector := range selectors {
+		if content := collapseSpace(doc.Find(selector).First().AttrOr("content", "")); content != "" {
+			return content
+		}
+	}
+	return ""
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLText(t *testing.T) {
+	page := `<html><head><title>t</title><script>var x = 1;</script></head><body>
+		<nav><a href="/">Home</a></nav>
+		<h1>Go 1.25 リリース</h1>
+		<p>Go チームは  Go 1.25 を
+		公開した。</p>
+		<ul><li><p>GOMAXPROCS の自動調整</p></li></ul>
+		<footer>© example</footer>
+	</body></html>`
+	assert.Equal(t, "Go 1.25 リリース\nGo チームは Go 1.25 を 公開した。\nGOMAXPROCS の自動調整", HTMLText(page))
+	assert.Equal(t, "plain text", HTMLText("<div>plain <b>text</b></div>"))
+	assert.Empty(t, HTMLText(""))
+}
+
+const articlePage = `<html><head>
+	<title>Go 1.25 リリース | Example News</title>
+	<meta property="og:title" content="Go 1.25 リリース">
+	<meta name="author" content="山田 太郎">
+	<meta property="article:published_time" content="2025-08-12T09:00:00Z">
+	<script>trackPageView();</script>
+</head><body>
+	<header class="site-header"><a href="/">Example News</a></header>
+	<nav><a href="/tech">Tech</a> <a href="/biz">Business</a></nav>
+	<div class="layout">
+		<div class="ad-banner">今だけ50%オフ!</div>
+		<div id="content">
+			<h1>Go 1.25 リリース</h1>
+			<p>Go チームは、Go 1.25 を公開した。コンテナ環境で GOMAXPROCS が自動調整されるようになり、CPU 制限に合わせて並列度が決まる。</p>
+			<p>また、実験的なガベージコレクタが追加され、小さなオブジェクトを多く使うプログラムでは、GC のオーバーヘッドが大きく減るという。</p>
+			<p>encoding/json の新しい実装も実験的に利用でき、デコードの性能が向上している。</p>
+		</div>
+		<aside class="sidebar"><p>人気記事ランキング: 1位 ...、2位 ...、3位 ...、4位 ...</p></aside>
+		<div class="related-posts"><p><a href="/a">関連記事: Go 1.24 リリース、新機能まとめ、移行ガイド</a></p></div>
+		<div class="comments"><p>コメント: すばらしい、待っていました、早速試します、ありがとうございます</p></div>
+	</div>
+	<footer>© Example News</footer>
+</body></html>`
+
+func TestExtractArticle(t *testing.T) {
+	article := ExtractArticle(articlePage)
+	if !assert.NotNil(t, article) {
+		return
+	}
+	assert.Equal(t, "Go 1.25 リリース", article.Title)
+	assert.Equal(t, "山田 太郎", article.Author)
+	assert.Equal(t, "2025-08-12T09:00:00Z", article.Published)
+	assert.Equal(t, strings.Join([]string{
+		"Go チームは、Go 1.25 を公開した。コンテナ環境で GOMAXPROCS が自動調整されるようになり、CPU 制限に合わせて並列度が決まる。",
+		"また、実験的なガベージコレクタが追加され、小さなオブジェクトを多く使うプログラムでは、GC のオーバーヘッドが大きく減るという。",
+		"encoding/json の新しい実装も実験的に利用でき、デコードの性能が向上している。",
+	}, "\n"), article.Text)
+
+	assert.Equal(t, "Title: Go 1.25 リリース\nAuthor: 山田 太郎\nPublished: 2025-08-12T09:00:00Z\n\n"+article.Text, article.String())
+}
+
+func TestExtractArticle_ArticleElement(t *testing.T) {
+	page := `<html><head><title>Fallback title</title></head><body>
+		<div class="menu"><p>Home, About, Contact, Archive, Subscribe to our newsletter today</p></div>
+		<article>
+			<header><h1>Post title</h1><span class="byline">Jane Doe</span><time datetime="2025-01-02">Jan 2</time></header>
+			<p>The first paragraph of the post explains what happened, where it happened, and why it matters to readers.</p>
+			<p>The second paragraph adds the details that make the story complete.</p>
+		</article>
+		<div class="share">Share on X, Facebook, LinkedIn</div>
+	</body></html>`
+	article := ExtractArticle(page)
+	if !assert.NotNil(t, article) {
+		return
+	}
+	assert.Equal(t, "Post title", article.Title)
+	assert.Equal(t, "Jane Doe", article.Author)
+	assert.Equal(t, "2025-01-02", article.Published)
+	assert.Equal(t, "The first paragraph of the post explains what happened, where it happened, and why it matters to readers.\n"+
+		"The second paragraph adds the details that make the story complete.", article.Text)
+}
+
+func TestExtractArticle_TitleParagraph(t *testing.T) {
+	page := `<html><head><title>Go 1.25</title></head><body><article>
+		<p>Go 1.25 is released with container-aware GOMAXPROCS and an experimental garbage collector.</p>
+		<p>The release also brings a new testing/synctest package and improvements to the toolchain.</p>
+	</article></body></html>`
+	article := ExtractArticle(page)
+	if !assert.NotNil(t, article) {
+		return
+	}
+	assert.Equal(t, "Go 1.25", article.Title)
+	assert.True(t, strings.HasPrefix(article.Text, "Go 1.25 is released with"), "a paragraph starting with the title should be kept whole: %q", article.Text)
+}
+
+func TestExtractArticle_NoArticle(t *testing.T) {
+	article := ExtractArticle("<html><head><title>Short</title></head><body><div>Just a line.</div></body></html>")
+	if !assert.NotNil(t, article) {
+		return
+	}
+	assert.Equal(t, "Short", article.Title)
+	assert.Equal(t, "Just a line.", article.Text)
+	assert.Nil(t, ExtractArticle(""))
+}
+
+func TestConvertPage(t *testing.T) {
+	assert.Equal(t, articlePage, ConvertPage(articlePage, "https://example.com/post", PageContentRaw))
+
+	text := ConvertPage(articlePage, "https://example.com/post", PageContentText)
+	assert.NotContains(t, text, "<p>")
+	assert.NotContains(t, text, "trackPageView")
+	assert.Contains(t, text, "コメント", "text keeps the whole page apart from navigation")
+
+	article := ConvertPage(articlePage, "https://example.com/post", PageContentArticle)
+	assert.True(t, strings.HasPrefix(article, "Title: Go 1.25 リリース\n"))
+	assert.NotContains(t, article, "人気記事ランキング")
+	assert.NotContains(t, article, "50%オフ")
+	assert.NotContains(t, article, "コメント")
+	assert.Empty(t, ConvertPage("", "", PageContentArticle))
+
+	mode, err := ParsePageContent("article")
+	assert.NoError(t, err)
+	assert.Equal(t, PageContentArticle, mode)
+	_, err = ParsePageContent("markup")
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// iso2022JPEscapes are the escape sequences switching ISO-2022-JP text to JIS X 0208.
+var iso2022JPEscapes = [][]byte{[]byte("\x1b$B"), []byte("\x1b$@")}
+
+// DecodeHTML transcodes an HTML page to UTF-8. The encoding is taken from a byte order
+// mark, the charset of the Content-Type header or a <meta charset> declaration, in this
+// order. Pages that declare none are sniffed: valid UTF-8 is kept, and Shift_JIS, EUC-JP
+// and ISO-2022-JP are recognized by their escape sequences or by which of them decodes
+// to more Japanese text. Anything else is decoded as windows-1252, like browsers do.
+// Parameters:
+//   - body: The raw body of the page.
+//   - contentType: The Content-Type header of the response. May be empty.
+//
+// Returns:
+//   - string: The page as UTF-8 text.
+//   - error: An error if the page cannot be decoded.
+func DecodeHTML(body []byte, contentType string) (string, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	if !certain && name == "windows-1252" {
+		enc, name = sniffEncoding(body)
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode page as %s: %w", name, err)
+	}
+	return strings.TrimPrefix(string(decoded), "\uFEFF"), nil
+}
+
+// sniffEncoding guesses the encoding of a page without an encoding declaration.
+// Parameters:
+//   - body: The raw body of the page.
+//
+// Returns:
+//   - encoding.Encoding: The guessed encoding.
+//   - string: The name of the encoding.
+func sniffEncoding(body []byte) (encoding.Encoding, string) {
+	// ISO-2022-JP is 7-bit, so it has to be told apart from ASCII before UTF-8.
+	for _, escape := range iso2022JPEscapes {
+		if bytes.Contains(body, escape) {
+			return japanese.ISO2022JP, "iso-2022-jp"
+		}
+	}
+	// DetermineEncoding only looks at the first 1024 bytes, which may all be ASCII markup.
+	if utf8.Valid(body) {
+		return encoding.Nop, "utf-8"
+	}
+
+	shiftJIS := japaneseScore(body, japanese.ShiftJIS)
+	eucJP := japaneseScore(body, japanese.EUCJP)
+	switch {
+	case shiftJIS == 0 && eucJP == 0:
+		enc, name := charset.Lookup("windows-1252")
+		return enc, name
+	case eucJP > shiftJIS:
+		return japanese.EUCJP, "euc-jp"
+	default:
+		return japanese.ShiftJIS, "shift_jis"
+	}
+}
+
+// japaneseScore decodes body with enc and scores how much it looks like Japanese text:
+// the number of kana and kanji, minus a penalty for every invalid byte sequence.
+// Parameters:
+//   - body: The raw body of the page.
+//   - enc: The candidate encoding.
+//
+// Returns:
+//   - int: The score, at most 0 if the text does not look like Japanese.
+func japaneseScore(body []byte, enc encoding.Encoding) int {
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return 0
+	}
+	score := 0
+	for _, r := range string(decoded) {
+		switch {
+		case r == utf8.RuneError:
+			score -= 10
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han):
+			score++
+		}
+	}
+	return max(score, 0)
+}
@@ -0,0 +1,134 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+const japaneseText = "日本語のニュース記事です。カタカナとひらがなと漢字を含みます。"
+
+// encode encodes s with enc, failing the test if it cannot be encoded.
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	assert.NoError(t, err)
+	return b
+}
+
+func TestDecodeHTML(t *testing.T) {
+	page := "<html><head><title>テスト</title></head><body><p>" + japaneseText + "</p></body></html>"
+	withMeta := func(charset string) string {
+		return "<html><head><meta charset=\"" + charset + "\"><title>テスト</title></head><body><p>" + japaneseText + "</p></body></html>"
+	}
+	// More than 1024 bytes of ASCII markup before the first non-ASCII character.
+	longHead := "<html><head><style>" + strings.Repeat("p { margin: 0; }\n", 80) + "</style></head><body><p>" + japaneseText + "</p></body></html>"
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+	}{
+		{
+			name:        "Shift_JIS from Content-Type",
+			body:        encode(t, japanese.ShiftJIS, page),
+			contentType: "text/html; charset=Shift_JIS",
+			want:        page,
+		},
+		{
+			name:        "EUC-JP from Content-Type",
+			body:        encode(t, japanese.EUCJP, page),
+			contentType: "text/html; charset=EUC-JP",
+			want:        page,
+		},
+		{
+			name:        "ISO-2022-JP from Content-Type",
+			body:        encode(t, japanese.ISO2022JP, page),
+			contentType: "text/html; charset=ISO-2022-JP",
+			want:        page,
+		},
+		{
+			name:        "Shift_JIS from meta",
+			body:        encode(t, japanese.ShiftJIS, withMeta("Shift_JIS")),
+			contentType: "text/html",
+			want:        withMeta("Shift_JIS"),
+		},
+		{
+			name: "EUC-JP from meta http-equiv",
+			body: encode(t, japanese.EUCJP, strings.Replace(page, "<head>", `<head><meta http-equiv="Content-Type" content="text/html; charset=EUC-JP">`, 1)),
+			want: strings.Replace(page, "<head>", `<head><meta http-equiv="Content-Type" content="text/html; charset=EUC-JP">`, 1),
+		},
+		{
+			name: "sniffed Shift_JIS",
+			body: encode(t, japanese.ShiftJIS, page),
+			want: page,
+		},
+		{
+			name: "sniffed EUC-JP",
+			body: encode(t, japanese.EUCJP, page),
+			want: page,
+		},
+		{
+			name: "sniffed ISO-2022-JP",
+			body: encode(t, japanese.ISO2022JP, page),
+			want: page,
+		},
+		{
+			name: "sniffed Shift_JIS after long ASCII head",
+			body: encode(t, japanese.ShiftJIS, longHead),
+			want: longHead,
+		},
+		{
+			name: "UTF-8 after long ASCII head",
+			body: []byte(longHead),
+			want: longHead,
+		},
+		{
+			name:        "UTF-8 with BOM",
+			body:        append([]byte("\xef\xbb\xbf"), page...),
+			contentType: "text/html",
+			want:        page,
+		},
+		{
+			name: "windows-1252 fallback",
+			body: encode(t, charmap.Windows1252, "<p>café</p>"),
+			want: "<p>café</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeHTML(tt.body, tt.contentType)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFetchHTML_ShiftJIS(t *testing.T) {
+	page := "<html><body><p>" + japaneseText + "</p></body></html>"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write(encode(t, japanese.ShiftJIS, page))
+	}))
+	defer ts.Close()
+
+	got, err := FetchHTML(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, page, got)
+
+	// The cache keeps the Content-Type, so cached pages are decoded the same way.
+	cache := NewHTTPCache(t.TempDir(), "")
+	for range 2 {
+		got, err = cache.FetchHTML(ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, page, got)
+	}
+}
@@ -1,5 +1,7 @@
 // Package fetcher provides functionality for fetching and processing external resources.
-// It includes methods for fetching RSS feeds and HTML pages, such as FeedFetcher and HTMLPageFetcher.
+// It includes methods for fetching RSS feeds and HTML pages, such as FeedFetcher and HTMLPageFetcher,
+// HTTPCache, which revalidates previously fetched feeds and pages with conditional requests,
+// and Politeness, which limits the requests per host and honours robots.txt.
 // The package is designed with dependency injection in mind, enabling easier testing of components
 // that rely on external data fetching.
 package fetcher
@@ -20,7 +22,7 @@ import (
 //   - error: An error if the fetch operation fails.
 type FeedFetcher func(string) (*gofeed.Feed, error)
 
-// FetchFeed fetches and parses an RSS feed from the given URL.
+// FetchFeed fetches and parses an RSS feed from the given URL. The request is sent with DefaultUserAgent.
 // Parameters:
 //   - feedURL: A string representing the URL of the RSS feed.
 //
@@ -28,7 +30,26 @@ type FeedFetcher func(string) (*gofeed.Feed, error)
 //   - *gofeed.Feed: The parsed RSS feed.
 //   - error: An error if the fetch operation fails.
 func FetchFeed(feedURL string) (*gofeed.Feed, error) {
+	return fetchFeed(feedURL, DefaultUserAgent)
+}
+
+// NewFeedFetcher returns a FeedFetcher that works like FetchFeed with another User-Agent.
+// Parameters:
+//   - userAgent: The User-Agent of the requests. An empty string selects DefaultUserAgent.
+//
+// Returns:
+//   - FeedFetcher: The fetcher.
+func NewFeedFetcher(userAgent string) FeedFetcher {
+	userAgent = userAgentOrDefault(userAgent)
+	return func(feedURL string) (*gofeed.Feed, error) {
+		return fetchFeed(feedURL, userAgent)
+	}
+}
+
+// fetchFeed fetches and parses an RSS feed like FetchFeed, sent with userAgent.
+func fetchFeed(feedURL, userAgent string) (*gofeed.Feed, error) {
 	fp := gofeed.NewParser()
+	fp.UserAgent = userAgent
 
 	feed, err := fp.ParseURL(feedURL)
 	if err != nil {
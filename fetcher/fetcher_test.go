@@ -1,10 +1,13 @@
 package fetcher
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -28,3 +31,50 @@ func TestFetchHTML_Error(t *testing.T) {
 	_, err := FetchHTML("http://invalid-url")
 	assert.Error(t, err, "Expected an error but got nil")
 }
+
+func TestFetchAll_BoundAndDeadline(t *testing.T) {
+	var urls []string
+	for i := range 2*maxConcurrentRequests + 5 {
+		urls = append(urls, fmt.Sprintf("https://example.com/%d", i))
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	slow := func(url string) (string, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return url, nil
+	}
+
+	// Two rounds of fetches start before the deadline, the URLs still queued after it fail.
+	pages, err := fetchAll(urls, slow, 150*time.Millisecond)
+	assert.Equal(t, maxConcurrentRequests, maxRunning, "no more than maxConcurrentRequests fetches should run at once")
+	assert.Len(t, pages, 2*maxConcurrentRequests)
+	assert.ErrorContains(t, err, "context timeout")
+
+	// URLs whose host stays busy past the deadline fail without waiting for it.
+	start := time.Now()
+	pages, err = fetchAll(urls[:1], func(url string) (string, error) {
+		return "", &hostBusyError{url: url, until: time.Now().Add(time.Hour)}
+	}, 150*time.Millisecond)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Empty(t, pages)
+	assert.ErrorContains(t, err, "context timeout")
+
+	// Busy URLs are fetched again once their host is ready, without holding a worker.
+	var tries sync.Map
+	pages, err = fetchAll(urls, func(url string) (string, error) {
+		if _, busy := tries.LoadOrStore(url, true); !busy {
+			return "", &hostBusyError{url: url, until: time.Now().Add(20 * time.Millisecond)}
+		}
+		return url, nil
+	}, time.Second)
+	assert.NoError(t, err)
+	assert.Len(t, pages, len(urls))
+}
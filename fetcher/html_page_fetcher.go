@@ -17,8 +17,8 @@ const (
 	// contextTimeout defines the timeout duration for the context used in FetchHTMLPages.
 	contextTimeout = 3 * time.Minute
 
-	// maxConcurrentGoroutines defines the maximum number of concurrent goroutines allowed in FetchHTMLPages.
-	maxConcurrentGoroutines = 10
+	// maxConcurrentRequests defines the maximum number of URLs fetched at the same time in FetchHTMLPages.
+	maxConcurrentRequests = 10
 )
 
 // HTMLPageFetcher defines a function type for fetching HTML content of a given URL.
@@ -30,42 +30,102 @@ const (
 //   - error: An error if the fetch operation fails.
 type HTMLPageFetcher func(string) (string, error)
 
-// FetchHTML retrieves the HTML content of the given URL as a string.
+// FetchHTML retrieves the HTML content of the given URL as a UTF-8 string,
+// transcoded from the encoding of the page with DecodeHTML. The request is sent with DefaultUserAgent.
 // Parameters:
 //   - url: A string representing the target URL.
 //
 // Returns:
 //   - string: The HTML content of the page.
 //   - error: An error if the request or reading the response fails.
-func FetchHTML(url string) (html string, err error) {
+func FetchHTML(url string) (string, error) {
+	return fetchHTML(url, DefaultUserAgent)
+}
+
+// NewHTMLPageFetcher returns an HTMLPageFetcher that works like FetchHTML with another User-Agent.
+// Parameters:
+//   - userAgent: The User-Agent of the requests. An empty string selects DefaultUserAgent.
+//
+// Returns:
+//   - HTMLPageFetcher: The fetcher.
+func NewHTMLPageFetcher(userAgent string) HTMLPageFetcher {
+	userAgent = userAgentOrDefault(userAgent)
+	return func(url string) (string, error) {
+		return fetchHTML(url, userAgent)
+	}
+}
+
+// fetchHTML retrieves the HTML content of the given URL like FetchHTML, sent with userAgent.
+func fetchHTML(url, userAgent string) (string, error) {
 	c := &http.Client{
 		Timeout: httpClientTimeout,
 	}
-	resp, err := c.Get(url)
+	res, err := httpGet(c, url, userAgent, nil)
 	if err != nil {
 		return "", err
 	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch URL: %s, status code: %d", url, res.StatusCode)
+	}
+	return DecodeHTML(res.Body, res.Header.Get("Content-Type"))
+}
+
+// httpResponse holds the parts of an HTTP response used by the fetchers.
+type httpResponse struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Header holds the response headers.
+	Header http.Header
+
+	// Body is the raw response body.
+	Body []byte
+}
+
+// httpGet sends a GET request and reads the whole response.
+// Parameters:
+//   - c: The HTTP client used to send the request.
+//   - url: The URL to fetch.
+//   - userAgent: The User-Agent of the request.
+//   - header: Additional request headers such as validators. May be nil.
+//
+// Returns:
+//   - *httpResponse: The status code, headers and body of the response.
+//   - error: An error if the request cannot be built or sent, or the response cannot be read.
+func httpGet(c *http.Client, url, userAgent string, header http.Header) (res *httpResponse, err error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer func() {
 		if closeError := resp.Body.Close(); closeError != nil {
 			err = errors.Join(err, fmt.Errorf("error closing response body: %w", closeError))
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to fetch URL: %s, status code: %d", url, resp.StatusCode)
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	return string(body), nil
+	return &httpResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
 }
 
 // FetchHTMLPages fetches the HTML content for multiple URLs concurrently.
 // It uses the provided HTMLPageFetcher function to retrieve the content of each URL.
-// If a timeout occurs or an error happens during fetching, the error is logged and processing continues.
+// If a timeout occurs or an error happens during fetching, the error is collected and processing continues.
 //
 // Parameters:
 //   - urls: A slice of strings representing the URLs to fetch.
@@ -75,42 +135,80 @@ func FetchHTML(url string) (html string, err error) {
 //   - map[string]string: A map where the keys are URLs and the values are their corresponding HTML content.
 //   - error: An aggregated error if any of the URLs cannot be processed.
 func FetchHTMLPages(urls []string, fetcher HTMLPageFetcher) (map[string]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), contextTimeout)
+	return fetchAll(urls, fetcher, contextTimeout)
+}
+
+// fetchAll fetches multiple URLs concurrently with fetch, at most maxConcurrentRequests
+// at a time. URLs that are not fetched within timeout fail, and URLs that fail are left
+// out of the result. A URL whose host is busy, reported by the fetcher with a
+// *hostBusyError, is fetched again once the host is ready, without holding one of the
+// workers in the meantime.
+// Parameters:
+//   - urls: A slice of strings representing the URLs to fetch.
+//   - fetch: A function that fetches the content of a given URL.
+//   - timeout: The time within which every URL must have been fetched.
+//
+// Returns:
+//   - map[string]T: A map where the keys are URLs and the values are their fetched content.
+//   - error: An aggregated error if any of the URLs cannot be processed.
+func fetchAll[T any](urls []string, fetch func(string) (T, error), timeout time.Duration) (map[string]T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	deadline, _ := ctx.Deadline()
 
-	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var err error
-	result := make(map[string]string)
-	semaphore := make(chan struct{}, maxConcurrentGoroutines)
+	result := make(map[string]T)
+	// pending counts the URLs without an outcome. Busy URLs are queued again, so the
+	// queue is closed once every URL has one.
+	var pending sync.WaitGroup
+	done := func(url string, content T, fetchErr error) {
+		mu.Lock()
+		if fetchErr != nil {
+			err = errors.Join(err, fetchErr)
+		} else {
+			result[url] = content
+		}
+		mu.Unlock()
+		pending.Done()
+	}
 
+	queue := make(chan string, len(urls))
 	for _, url := range urls {
-		wg.Add(1)
-		semaphore <- struct{}{}
-
-		go func(url string) {
-			defer wg.Done()
-			defer func() { <-semaphore }()
-
-			select {
-			case <-ctx.Done():
-				mu.Lock()
-				err = errors.Join(err, fmt.Errorf("context timeout; fetching URL: %s", url))
-				mu.Unlock()
-			default:
-				page, htmlErr := fetcher(url)
-				mu.Lock()
-				if htmlErr != nil {
-					err = errors.Join(err, htmlErr)
-				} else {
-					result[url] = page
+		queue <- url
+	}
+	pending.Add(len(urls))
+	go func() {
+		pending.Wait()
+		close(queue)
+	}()
+
+	var workers sync.WaitGroup
+	for range min(maxConcurrentRequests, len(urls)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			var zero T
+			for url := range queue {
+				if ctx.Err() != nil {
+					done(url, zero, fmt.Errorf("context timeout; fetching URL: %s", url))
+					continue
+				}
+				content, fetchErr := fetch(url)
+				var busy *hostBusyError
+				if !errors.As(fetchErr, &busy) {
+					done(url, content, fetchErr)
+					continue
 				}
-				mu.Unlock()
+				if busy.until.After(deadline) {
+					done(url, zero, fmt.Errorf("context timeout; fetching URL: %s: %w", url, fetchErr))
+					continue
+				}
+				time.AfterFunc(time.Until(busy.until), func() { queue <- url })
 			}
-		}(url)
+		}()
 	}
-
-	wg.Wait()
+	workers.Wait()
 
 	if err != nil {
 		err = errors.Join(err, fmt.Errorf("some URLs may not have been fetched successfully"))
@@ -0,0 +1,214 @@
+package fetcher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// HTTPCache fetches feeds and pages with conditional GET requests. The body of every
+// response with an ETag or Last-Modified header is stored on disk with its validators,
+// which are sent as If-None-Match and If-Modified-Since on the next request for the
+// same URL, so unchanged resources are served from the cache after a 304 response.
+// The cached copy is also served when the server rate limits or fails.
+type HTTPCache struct {
+	// dir is the directory holding one file per cached URL.
+	dir string
+
+	// client is the HTTP client used to send the requests.
+	client *http.Client
+
+	// userAgent is the User-Agent of the requests.
+	userAgent string
+}
+
+// httpCacheEntry is the content of a cache file.
+type httpCacheEntry struct {
+	// URL is the URL of the cached resource.
+	URL string `json:"url"`
+
+	// ETag is the entity tag of the response. It may be empty.
+	ETag string `json:"etag,omitempty"`
+
+	// LastModified is the Last-Modified header of the response. It may be empty.
+	LastModified string `json:"last_modified,omitempty"`
+
+	// ContentType is the Content-Type header of the response.
+	ContentType string `json:"content_type,omitempty"`
+
+	// Body is the body of the response.
+	Body []byte `json:"body"`
+
+	// Stored is the time the response was stored.
+	Stored time.Time `json:"stored"`
+}
+
+// NewHTTPCache creates a new instance of HTTPCache.
+// Parameters:
+//   - dir: The cache directory. It is created on the first write.
+//   - userAgent: The User-Agent of the requests. An empty string selects DefaultUserAgent.
+//
+// Returns:
+//   - *HTTPCache: A pointer to the newly created HTTPCache instance.
+func NewHTTPCache(dir, userAgent string) *HTTPCache {
+	return &HTTPCache{
+		dir: dir,
+		client: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+		userAgent: userAgentOrDefault(userAgent),
+	}
+}
+
+// FetchFeed fetches and parses an RSS feed like FetchFeed, revalidating a cached copy.
+// It can be used as a FeedFetcher.
+// Parameters:
+//   - feedURL: A string representing the URL of the RSS feed.
+//
+// Returns:
+//   - *gofeed.Feed: The parsed RSS feed.
+//   - error: An error if the feed cannot be fetched or parsed.
+func (c *HTTPCache) FetchFeed(feedURL string) (*gofeed.Feed, error) {
+	entry, err := c.get(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch RSS feed from URL %s: %w", feedURL, err)
+	}
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(entry.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RSS feed from URL %s: %w", feedURL, err)
+	}
+	return feed, nil
+}
+
+// FetchHTML retrieves the HTML content of a page like FetchHTML, revalidating a cached copy.
+// It can be used as an HTMLPageFetcher.
+// Parameters:
+//   - url: A string representing the target URL.
+//
+// Returns:
+//   - string: The HTML content of the page.
+//   - error: An error if the request fails.
+func (c *HTTPCache) FetchHTML(url string) (string, error) {
+	entry, err := c.get(url)
+	if err != nil {
+		return "", err
+	}
+	return DecodeHTML(entry.Body, entry.ContentType)
+}
+
+// get fetches url with a conditional GET request if it is cached, and stores the response.
+// Failing to read or write the cache does not fail the request.
+// Parameters:
+//   - url: The URL to fetch.
+//
+// Returns:
+//   - *httpCacheEntry: The fetched or cached response.
+//   - error: An error if the request fails and there is no cached copy to serve.
+func (c *HTTPCache) get(url string) (*httpCacheEntry, error) {
+	path := c.path(url)
+	cached := c.load(path)
+
+	header := http.Header{}
+	if cached != nil {
+		if cached.ETag != "" {
+			header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	res, err := httpGet(c.client, url, c.userAgent, header)
+	if err != nil {
+		if cached != nil {
+			log.Printf("serving cached copy of %s: %v", url, err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+		return cached, nil
+	case res.StatusCode == http.StatusOK:
+	case cached != nil && (res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError):
+		log.Printf("serving cached copy of %s, status code: %d", url, res.StatusCode)
+		return cached, nil
+	default:
+		return nil, fmt.Errorf("failed to fetch URL: %s, status code: %d", url, res.StatusCode)
+	}
+
+	entry := &httpCacheEntry{
+		URL:          url,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		ContentType:  res.Header.Get("Content-Type"),
+		Body:         res.Body,
+		Stored:       time.Now(),
+	}
+	// Responses without validators cannot be revalidated, so they are not worth storing.
+	if entry.ETag != "" || entry.LastModified != "" {
+		if err := c.store(path, entry); err != nil {
+			log.Printf("failed to cache %s: %v", url, err)
+		}
+	}
+	return entry, nil
+}
+
+// path returns the cache file of a URL.
+func (c *HTTPCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load reads the cache file at path, or returns nil if there is no readable entry.
+func (c *HTTPCache) load(path string) *httpCacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("failed to read cached page: %v", err)
+		}
+		return nil
+	}
+	var entry httpCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Printf("ignoring corrupt cached page %s: %v", path, err)
+		return nil
+	}
+	return &entry
+}
+
+// store writes entry to the cache file at path. The file is written under a
+// temporary name and renamed, so concurrent readers never see a partial entry.
+func (c *HTTPCache) store(path string, entry *httpCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, "*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	_, writeErr := tmp.Write(data)
+	if err := errors.Join(writeErr, tmp.Close()); err != nil {
+		return errors.Join(fmt.Errorf("failed to write cache file: %w", err), os.Remove(tmp.Name()))
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Join(fmt.Errorf("failed to move cache file into place: %w", err), os.Remove(tmp.Name()))
+	}
+	return nil
+}
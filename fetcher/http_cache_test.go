@@ -0,0 +1,101 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPCache_FetchHTML(t *testing.T) {
+	body := "<html><body>v1</body></html>"
+	served := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` && body == "<html><body>v1</body></html>" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		served++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	cache := NewHTTPCache(t.TempDir(), "")
+	page, err := cache.FetchHTML(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><body>v1</body></html>", page)
+
+	// A second cache on the same directory, like the next run, revalidates the stored copy.
+	cache = NewHTTPCache(cache.dir, "")
+	page, err = cache.FetchHTML(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><body>v1</body></html>", page)
+	assert.Equal(t, 1, served, "an unchanged page should be served from the cache")
+
+	body = "<html><body>v2</body></html>"
+	page, err = cache.FetchHTML(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, body, page)
+	assert.Equal(t, 2, served)
+}
+
+func TestHTTPCache_FetchFeed(t *testing.T) {
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	served := 0
+	limited := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case limited:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case r.Header.Get("If-Modified-Since") == lastModified:
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			served++
+			w.Header().Set("Last-Modified", lastModified)
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Feed</title>
+				<item><title>Test Item</title><link>http://example.com/test</link></item></channel></rss>`))
+		}
+	}))
+	defer ts.Close()
+
+	cache := NewHTTPCache(t.TempDir(), "")
+	for range 2 {
+		feed, err := cache.FetchFeed(ts.URL)
+		assert.NoError(t, err)
+		if assert.Len(t, feed.Items, 1) {
+			assert.Equal(t, "Test Item", feed.Items[0].Title)
+		}
+	}
+	assert.Equal(t, 1, served)
+
+	// A rate limited request is served from the cache.
+	limited = true
+	feed, err := cache.FetchFeed(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Feed", feed.Title)
+
+	// Without a cached copy the error is reported.
+	_, err = NewHTTPCache(t.TempDir(), "").FetchFeed(ts.URL)
+	assert.ErrorContains(t, err, "status code: 429")
+}
+
+func TestHTTPCache_NoValidators(t *testing.T) {
+	served := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("If-None-Match"))
+		assert.Empty(t, r.Header.Get("If-Modified-Since"))
+		served++
+		_, _ = w.Write([]byte("page"))
+	}))
+	defer ts.Close()
+
+	cache := NewHTTPCache(t.TempDir(), "")
+	for range 2 {
+		page, err := cache.FetchHTML(ts.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, "page", page)
+	}
+	assert.Equal(t, 2, served, "pages without validators should always be fetched")
+}
@@ -0,0 +1,172 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+const (
+	// maxImageBytes defines the maximum size of a downloaded image.
+	maxImageBytes = 10 << 20
+
+	// MaxImageDimension defines the maximum width and height of a fetched image.
+	// Larger images are downscaled, which keeps them within the limits of every vision API.
+	MaxImageDimension = 1024
+
+	// jpegQuality defines the quality of re-encoded JPEG images.
+	jpegQuality = 85
+)
+
+// Image is a fetched image.
+type Image struct {
+	// MIMEType is the media type of the image, "image/jpeg" or "image/png".
+	MIMEType string
+
+	// Data is the encoded image.
+	Data []byte
+}
+
+// ImageFetcher defines a function type for fetching an image from a given URL.
+// Parameters:
+//   - string: The URL of the image to fetch.
+//
+// Returns:
+//   - *Image: The fetched image.
+//   - error: An error if the fetch operation fails.
+type ImageFetcher func(string) (*Image, error)
+
+// FetchImage retrieves the image at the given URL and downscales it to MaxImageDimension.
+// Images larger than maxImageBytes are rejected without being read entirely.
+// Parameters:
+//   - url: A string representing the URL of the image.
+//
+// Returns:
+//   - *Image: The image as JPEG or PNG.
+//   - error: An error if the request fails, the image is too large, or it cannot be decoded.
+func FetchImage(url string) (*Image, error) {
+	return fetchImage(url, DefaultUserAgent)
+}
+
+// NewImageFetcher returns an ImageFetcher that works like FetchImage with another User-Agent.
+// Parameters:
+//   - userAgent: The User-Agent of the requests. An empty string selects DefaultUserAgent.
+//
+// Returns:
+//   - ImageFetcher: The fetcher.
+func NewImageFetcher(userAgent string) ImageFetcher {
+	userAgent = userAgentOrDefault(userAgent)
+	return func(url string) (*Image, error) {
+		return fetchImage(url, userAgent)
+	}
+}
+
+// fetchImage retrieves and downscales the image at the given URL like FetchImage, sent with userAgent.
+func fetchImage(url, userAgent string) (img *Image, err error) {
+	c := &http.Client{
+		Timeout: httpClientTimeout,
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeError := resp.Body.Close(); closeError != nil {
+			err = errors.Join(err, fmt.Errorf("error closing response body: %w", closeError))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image: %s, status code: %d", url, resp.StatusCode)
+	}
+	if resp.ContentLength > maxImageBytes {
+		return nil, fmt.Errorf("image %s is too large: %d bytes", url, resp.ContentLength)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image %s is larger than %d bytes", url, maxImageBytes)
+	}
+
+	img, err = DownscaleImage(data, MaxImageDimension)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %s: %w", url, err)
+	}
+	return img, nil
+}
+
+// FetchImages fetches the images at multiple URLs concurrently, like FetchHTMLPages.
+// Parameters:
+//   - urls: A slice of strings representing the image URLs to fetch.
+//   - fetcher: A function that fetches the image at a given URL.
+//
+// Returns:
+//   - map[string]*Image: A map where the keys are URLs and the values are their images.
+//   - error: An aggregated error if any of the URLs cannot be processed.
+func FetchImages(urls []string, fetcher ImageFetcher) (map[string]*Image, error) {
+	return fetchAll(urls, fetcher, contextTimeout)
+}
+
+// DownscaleImage decodes a JPEG, PNG, GIF or WebP image and scales it down to fit within
+// maxDimension pixels, keeping its aspect ratio. JPEG and PNG images that already fit are
+// returned unchanged; other images are re-encoded as PNG if they were PNG, or JPEG otherwise.
+// Parameters:
+//   - data: The encoded image.
+//   - maxDimension: The maximum width and height in pixels.
+//
+// Returns:
+//   - *Image: The image as JPEG or PNG.
+//   - error: An error if the image cannot be decoded or encoded.
+func DownscaleImage(data []byte, maxDimension int) (*Image, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxDimension && height <= maxDimension && (format == "jpeg" || format == "png") {
+		return &Image{MIMEType: "image/" + format, Data: data}, nil
+	}
+
+	dst := src
+	if width > maxDimension || height > maxDimension {
+		if width >= height {
+			width, height = maxDimension, max(1, height*maxDimension/width)
+		} else {
+			width, height = max(1, width*maxDimension/height), maxDimension
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, bounds, draw.Over, nil)
+		dst = scaled
+	}
+
+	var buf bytes.Buffer
+	if format == "png" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, err
+		}
+		return &Image{MIMEType: "image/png", Data: buf.Bytes()}, nil
+	}
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return &Image{MIMEType: "image/jpeg", Data: buf.Bytes()}, nil
+}
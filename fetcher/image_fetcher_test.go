@@ -0,0 +1,71 @@
+package fetcher
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// encodePNG encodes a blank image of the given size as PNG.
+func encodePNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func TestDownscaleImage(t *testing.T) {
+	small := encodePNG(t, 100, 50)
+	img, err := DownscaleImage(small, 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", img.MIMEType)
+	assert.Equal(t, small, img.Data, "images that fit should be returned unchanged")
+
+	img, err = DownscaleImage(encodePNG(t, 2000, 500), 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", img.MIMEType)
+	config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 1024, config.Width)
+	assert.Equal(t, 256, config.Height)
+
+	var buf bytes.Buffer
+	palette := image.NewPaletted(image.Rect(0, 0, 10, 20), color.Palette{color.White, color.Black})
+	assert.NoError(t, gif.Encode(&buf, palette, nil))
+	img, err = DownscaleImage(buf.Bytes(), 1024)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", img.MIMEType, "GIF images should be re-encoded as JPEG")
+
+	_, err = DownscaleImage([]byte("not an image"), 1024)
+	assert.Error(t, err)
+}
+
+func TestFetchImage(t *testing.T) {
+	data := encodePNG(t, 1600, 1600)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/huge.png" {
+			w.Header().Set("Content-Length", strconv.Itoa(maxImageBytes+1))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		_, err := w.Write(data)
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	img, err := FetchImage(ts.URL + "/image.png")
+	assert.NoError(t, err)
+	config, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, MaxImageDimension, config.Width)
+
+	_, err = FetchImage(ts.URL + "/huge.png")
+	assert.ErrorContains(t, err, "too large")
+}
@@ -0,0 +1,420 @@
+package fetcher
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdownSkipped are the elements that are dropped from Markdown, like nonContentSelector.
+var markdownSkipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Svg: true,
+	atom.Iframe: true, atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Head: true,
+}
+
+// markdownBlocks are the elements that start a block of their own.
+var markdownBlocks = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Blockquote: true, atom.Dd: true, atom.Details: true,
+	atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Hr: true, atom.Li: true, atom.Main: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Summary: true, atom.Table: true, atom.Ul: true,
+}
+
+// HTMLMarkdown converts an HTML page to Markdown. Headings, paragraphs, lists, tables,
+// code blocks, block quotes, links, images and emphasis are kept; scripts, styles and
+// navigation are dropped like in HTMLText. Relative link and image URLs are resolved
+// against the <base href> of the page, or pageURL if it has none.
+// Parameters:
+//   - page: The HTML content of the page. May be empty.
+//   - pageURL: The URL the page was fetched from. May be empty to keep relative URLs.
+//
+// Returns:
+//   - string: The page as Markdown, or an empty string if it cannot be parsed.
+func HTMLMarkdown(page, pageURL string) string {
+	if strings.TrimSpace(page) == "" {
+		return ""
+	}
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return ""
+	}
+
+	w := &markdownWriter{}
+	if base, err := url.Parse(pageURL); err == nil {
+		w.base = base
+	}
+	if href := findElement(doc, atom.Base); href != nil {
+		w.base = w.resolveURL(attr(href, "href"))
+	}
+	return strings.Join(w.blocks(doc), "\n\n")
+}
+
+// markdownWriter renders HTML nodes as Markdown.
+type markdownWriter struct {
+	// base is the URL relative URLs are resolved against. It may be nil.
+	base *url.URL
+}
+
+// blocks renders the children of n as Markdown blocks. Runs of text and inline elements
+// between block elements form paragraphs.
+// Parameters:
+//   - n: The node whose children are rendered.
+//
+// Returns:
+//   - []string: The non-empty blocks in order.
+func (w *markdownWriter) blocks(n *html.Node) []string {
+	var blocks []string
+	var paragraph strings.Builder
+	flush := func() {
+		if text := cleanInline(paragraph.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		paragraph.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && markdownSkipped[c.DataAtom] {
+			continue
+		}
+		switch {
+		case c.Type == html.ElementNode && markdownBlocks[c.DataAtom]:
+			flush()
+			if block := w.block(c); block != "" {
+				blocks = append(blocks, block)
+			}
+		case c.Type == html.ElementNode && hasBlock(c):
+			// Containers such as <body> or custom elements are transparent.
+			flush()
+			blocks = append(blocks, w.blocks(c)...)
+		default:
+			paragraph.WriteString(w.inline(c))
+		}
+	}
+	flush()
+	return blocks
+}
+
+// block renders a block element.
+// Parameters:
+//   - n: The block element.
+//
+// Returns:
+//   - string: The Markdown of the element, or an empty string if it has no content.
+func (w *markdownWriter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.ReplaceAll(cleanInline(w.inlineChildren(n)), "\n", " ")
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + text
+	case atom.Hr:
+		return "---"
+	case atom.Pre:
+		return codeBlock(n)
+	case atom.Ul, atom.Ol:
+		return w.list(n)
+	case atom.Table:
+		return w.table(n)
+	case atom.Blockquote:
+		return prefixLines(strings.Join(w.blocks(n), "\n\n"), "> ", ">")
+	default:
+		return strings.Join(w.blocks(n), "\n\n")
+	}
+}
+
+// list renders a list, numbering the items of ordered lists. Nested blocks of an item,
+// such as nested lists, are indented under it.
+func (w *markdownWriter) list(n *html.Node) string {
+	var items []string
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		content := strings.Join(w.blocks(c), "\n")
+		if content == "" {
+			continue
+		}
+		items = append(items, marker+prefixLines(content, strings.Repeat(" ", len(marker)), "")[len(marker):])
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table as a GitHub Flavored Markdown table. The first row is the header.
+func (w *markdownWriter) table(n *html.Node) string {
+	var rows [][]string
+	columns := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || c.DataAtom == atom.Table {
+				continue
+			}
+			if c.DataAtom != atom.Tr {
+				walk(c)
+				continue
+			}
+			var row []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Th || cell.DataAtom == atom.Td) {
+					text := strings.ReplaceAll(cleanInline(w.inlineChildren(cell)), "\n", " ")
+					row = append(row, strings.ReplaceAll(text, "|", `\|`))
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+				columns = max(columns, len(row))
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	writeRow := func(row []string) {
+		sb.WriteString("|")
+		for i := range columns {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(rows[0])
+	writeRow(slicesRepeat("---", columns))
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// inline renders an inline node.
+func (w *markdownWriter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return n.Data
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if markdownSkipped[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.A:
+		text := strings.TrimSpace(cleanInline(w.inlineChildren(n)))
+		href := attr(n, "href")
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		return "[" + text + "](" + w.resolve(href) + ")"
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" || strings.HasPrefix(src, "data:") {
+			return ""
+		}
+		return "![" + attr(n, "alt") + "](" + w.resolve(src) + ")"
+	case atom.Strong, atom.B:
+		return wrapInline(w.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(w.inlineChildren(n), "*")
+	case atom.Del, atom.S:
+		return wrapInline(w.inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return codeSpan(textContent(n))
+	default:
+		return w.inlineChildren(n)
+	}
+}
+
+// inlineChildren renders the children of n as inline content.
+func (w *markdownWriter) inlineChildren(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && markdownBlocks[c.DataAtom] {
+			// Blocks in inline content, e.g. a <div> in a link, are separated by spaces.
+			sb.WriteString(" " + w.inlineChildren(c) + " ")
+			continue
+		}
+		sb.WriteString(w.inline(c))
+	}
+	return sb.String()
+}
+
+// resolve resolves a URL against the base URL of the page.
+func (w *markdownWriter) resolve(ref string) string {
+	if u := w.resolveURL(ref); u != nil {
+		return u.String()
+	}
+	return ref
+}
+
+// resolveURL parses ref relative to the base URL, or returns nil if it is invalid.
+func (w *markdownWriter) resolveURL(ref string) *url.URL {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return w.base
+	}
+	if w.base == nil {
+		return u
+	}
+	return w.base.ResolveReference(u)
+}
+
+// codeBlock renders a <pre> element as a fenced code block, with the language
+// taken from a "language-*" or "lang-*" class of the element or its <code> child.
+func codeBlock(n *html.Node) string {
+	code := strings.Trim(textContent(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	language := codeLanguage(n)
+	if child := findElement(n, atom.Code); language == "" && child != nil {
+		language = codeLanguage(child)
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// codeLanguage returns the language of a "language-*" or "lang-*" class of n.
+func codeLanguage(n *html.Node) string {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if language, ok := strings.CutPrefix(class, prefix); ok {
+				return language
+			}
+		}
+	}
+	return ""
+}
+
+// codeSpan renders text as inline code, with a fence longer than any run of backticks in it.
+func codeSpan(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// wrapInline wraps inline content in a delimiter such as "**", keeping surrounding spaces outside.
+func wrapInline(content, delimiter string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return content
+	}
+	leading := content[:len(content)-len(strings.TrimLeft(content, " \t\n"))]
+	trailing := content[len(strings.TrimRight(content, " \t\n")):]
+	return leading + delimiter + trimmed + delimiter + trailing
+}
+
+// cleanInline collapses the whitespace of inline content within each line, keeping the
+// line breaks of <br> elements, and drops empty lines.
+func cleanInline(s string) string {
+	var lines []string
+	for line := range strings.SplitSeq(s, "\n") {
+		if line = collapseSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prefixLines prefixes every line of s, using emptyPrefix for empty lines.
+func prefixLines(s, prefix, emptyPrefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hasBlock reports whether n contains a block element.
+func hasBlock(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (markdownBlocks[c.DataAtom] || hasBlock(c)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findElement returns the first descendant of n with the given tag, or nil if there is none.
+func findElement(n *html.Node, tag atom.Atom) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == tag {
+			return c
+		}
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent returns the text of n and its descendants as is.
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+// attr returns the value of the attribute key of n, or an empty string if it has none.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// slicesRepeat returns a slice holding s n times.
+func slicesRepeat(s string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = s
+	}
+	return out
+}
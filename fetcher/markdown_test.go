@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		pageURL string
+		want    string
+	}{
+		{
+			name: "headings and paragraphs",
+			page: `<html><head><title>T</title><script>var x = 1;</script></head><body>
+				<nav><a href="/">Home</a></nav>
+				<h1>Go 1.25 <em>released</em></h1>
+				<p>The   Go team <strong>released</strong> Go 1.25.<br>Read on.</p>
+				<h3>Details</h3><p>Use <code>go install</code> to upgrade.</p>
+				<footer>© Example</footer></body></html>`,
+			want: "# Go 1.25 *released*\n\nThe Go team **released** Go 1.25.\nRead on.\n\n### Details\n\nUse `go install` to upgrade.",
+		},
+		{
+			name:    "links and images resolved against the page URL",
+			page:    `<p><a href="../docs/intro.html">Intro</a>, <a href="https://go.dev/">Go</a>, <a href="#top">top</a> <img src="/img/a.png" alt="Logo"></p>`,
+			pageURL: "https://example.com/blog/post/1",
+			want:    "[Intro](https://example.com/blog/docs/intro.html), [Go](https://go.dev/), top ![Logo](https://example.com/img/a.png)",
+		},
+		{
+			name:    "base href",
+			page:    `<html><head><base href="https://cdn.example.com/site/"></head><body><a href="page">Page</a></body></html>`,
+			pageURL: "https://example.com/",
+			want:    "[Page](https://cdn.example.com/site/page)",
+		},
+		{
+			name: "nested lists",
+			page: `<ul><li>One<ul><li>One A</li><li>One B</li></ul></li><li>Two</li></ul><ol start="3"><li>Three</li><li><p>Four</p></li></ol>`,
+			want: "- One\n  - One A\n  - One B\n- Two\n\n3. Three\n4. Four",
+		},
+		{
+			name: "table",
+			page: `<table><thead><tr><th>Name</th><th>Value</th></tr></thead><tbody><tr><td>a|b</td><td><a href="https://example.com/x">x</a></td></tr><tr><td>c</td></tr></tbody></table>`,
+			want: "| Name | Value |\n| --- | --- |\n| a\\|b | [x](https://example.com/x) |\n| c |  |",
+		},
+		{
+			name: "code block",
+			page: "<pre><code class=\"language-go\">func main() {\n\tfmt.Println(\"hi\")\n}\n</code></pre>",
+			want: "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```",
+		},
+		{
+			name: "block quote",
+			page: `<blockquote><p>First</p><p>Second</p></blockquote>`,
+			want: "> First\n>\n> Second",
+		},
+		{
+			name: "empty",
+			page: "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HTMLMarkdown(tt.page, tt.pageURL))
+		})
+	}
+}
+
+func TestConvertPage_Markdown(t *testing.T) {
+	markdown := ConvertPage(articlePage, "https://example.com/post", PageContentMarkdown)
+	assert.Contains(t, markdown, "# Go 1.25 リリース")
+	assert.NotContains(t, markdown, "<p>")
+	assert.NotContains(t, markdown, "trackPageView")
+
+	mode, err := ParsePageContent("markdown")
+	assert.NoError(t, err)
+	assert.Equal(t, PageContentMarkdown, mode)
+}
@@ -0,0 +1,271 @@
+package fetcher
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUserAgent is the User-Agent of the fetchers that are not given another one.
+	DefaultUserAgent = "feed-summarizer/1.0"
+
+	// maxCrawlDelay caps the Crawl-delay of robots.txt files, so that a single host cannot
+	// hold up the whole run.
+	maxCrawlDelay = time.Minute
+
+	// hostBusyRetry is how long a page waits before it is tried again while all
+	// requests allowed for its host are running.
+	hostBusyRetry = 10 * time.Millisecond
+)
+
+// userAgentOrDefault returns userAgent, or DefaultUserAgent if it is empty.
+func userAgentOrDefault(userAgent string) string {
+	if userAgent == "" {
+		return DefaultUserAgent
+	}
+	return userAgent
+}
+
+// productToken returns the product token of the User-Agent, which robots.txt groups are matched against.
+func productToken(ua string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(ua), " ")
+	token, _, _ = strings.Cut(token, "/")
+	return token
+}
+
+// DisallowedError is returned for pages that the robots.txt of their host disallows.
+type DisallowedError struct {
+	// URL is the URL of the page.
+	URL string
+
+	// Reason explains why the page is disallowed, e.g. the matching robots.txt rule.
+	Reason string
+}
+
+// Error returns the error message.
+func (e *DisallowedError) Error() string {
+	return fmt.Sprintf("fetching %s is disallowed: %s", e.URL, e.Reason)
+}
+
+// hostBusyError is returned by the fetchers of a Politeness for pages whose host cannot
+// take another request yet. fetchAll tries the page again at until without holding a worker.
+type hostBusyError struct {
+	// url is the URL of the page.
+	url string
+
+	// until is the earliest time the host may be ready.
+	until time.Time
+}
+
+// Error returns the error message.
+func (e *hostBusyError) Error() string {
+	return fmt.Sprintf("the host of %s is busy for %s", e.url, time.Until(e.until).Round(time.Millisecond))
+}
+
+// Politeness limits how hard the pages of a single host are fetched. At most maxPerHost
+// requests run concurrently per host, and consecutive requests to a host start at least
+// delay apart, or the Crawl-delay of its robots.txt if that is longer. The robots.txt of
+// each host is fetched once and cached for the lifetime of the Politeness, and pages it
+// disallows for the User-Agent are not fetched. Hosts that answer 401 or 403 for their
+// robots.txt are disallowed entirely, while a missing robots.txt allows everything.
+// Network errors, 429 and server errors are temporary: the page is fetched and
+// robots.txt is requested again with the next page of the host.
+// The fetchers of a Politeness do not wait for a busy host themselves, so that
+// FetchHTMLPages can fetch the pages of other hosts in the meantime; they are meant
+// to be used with FetchHTMLPages, which fetches such pages again once their host is ready.
+type Politeness struct {
+	// maxPerHost is the maximum number of concurrent requests per host.
+	maxPerHost int
+
+	// delay is the minimum time between the starts of two requests to the same host.
+	delay time.Duration
+
+	// userAgent is the User-Agent of the requests for robots.txt, whose product token
+	// selects the rules that apply.
+	userAgent string
+
+	// client is the HTTP client used to fetch robots.txt.
+	client *http.Client
+
+	// mu guards hosts.
+	mu sync.Mutex
+
+	// hosts holds the state of every host seen so far, keyed by scheme and host.
+	hosts map[string]*hostState
+}
+
+// hostState is the state of a single host.
+type hostState struct {
+	// slots limits the number of concurrent requests to the host.
+	slots chan struct{}
+
+	// mu guards last.
+	mu sync.Mutex
+
+	// last is the time the last request to the host started.
+	last time.Time
+
+	// robotsMu guards robots and ensures robots.txt is fetched by one request at a time.
+	robotsMu sync.Mutex
+
+	// robots are the robots.txt rules of the host, or nil until robots.txt was fetched.
+	robots *robotsRules
+}
+
+// NewPoliteness creates a new instance of Politeness.
+// Parameters:
+//   - maxPerHost: The maximum number of concurrent requests per host. Values below 1 are treated as 1.
+//   - delay: The minimum time between the starts of two requests to the same host. May be 0.
+//   - userAgent: The User-Agent of the wrapped fetcher, e.g. "my-digest/2.0 (+https://example.com/bot)".
+//     It is sent with the requests for robots.txt, and its product token selects the rules that apply.
+//     An empty string selects DefaultUserAgent.
+//
+// Returns:
+//   - *Politeness: A pointer to the newly created Politeness instance.
+func NewPoliteness(maxPerHost int, delay time.Duration, userAgent string) *Politeness {
+	return &Politeness{
+		maxPerHost: max(maxPerHost, 1),
+		delay:      delay,
+		userAgent:  userAgentOrDefault(userAgent),
+		client: &http.Client{
+			Timeout: httpClientTimeout,
+		},
+		hosts: make(map[string]*hostState),
+	}
+}
+
+// Wrap returns an HTMLPageFetcher that fetches pages with fetch within the limits of the
+// Politeness. Pages disallowed by robots.txt fail with a *DisallowedError without being fetched,
+// and pages whose host is busy fail with an error that FetchHTMLPages retries.
+// Parameters:
+//   - fetch: The fetcher of the pages, e.g. FetchHTML or HTTPCache.FetchHTML.
+//
+// Returns:
+//   - HTMLPageFetcher: The polite fetcher.
+func (p *Politeness) Wrap(fetch HTMLPageFetcher) HTMLPageFetcher {
+	return func(pageURL string) (string, error) {
+		u, err := url.Parse(pageURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			// Let fetch report URLs that cannot be requested.
+			return fetch(pageURL)
+		}
+
+		host := p.host(u)
+		robots := p.robots(host, u)
+		if robots.forbidden != "" {
+			return "", &DisallowedError{URL: pageURL, Reason: robots.forbidden}
+		}
+		if rule := robots.disallowedBy(u.RequestURI()); rule != nil {
+			return "", &DisallowedError{URL: pageURL, Reason: fmt.Sprintf("robots.txt of %s has %q", u.Host, rule.String())}
+		}
+
+		if until, ok := host.acquire(max(p.delay, robots.crawlDelay)); !ok {
+			return "", &hostBusyError{url: pageURL, until: until}
+		}
+		defer func() { <-host.slots }()
+		return fetch(pageURL)
+	}
+}
+
+// host returns the state of the host of u, creating it on first use.
+func (p *Politeness) host(u *url.URL) *hostState {
+	key := u.Scheme + "://" + u.Host
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	host, ok := p.hosts[key]
+	if !ok {
+		host = &hostState{slots: make(chan struct{}, p.maxPerHost)}
+		p.hosts[key] = host
+	}
+	return host
+}
+
+// robots returns the robots.txt rules of the host of u, fetching them on first use.
+// Rules are not cached while robots.txt cannot be fetched, so it is requested again later.
+// Parameters:
+//   - host: The state of the host.
+//   - u: A URL of the host.
+//
+// Returns:
+//   - *robotsRules: The rules that apply to the User-Agent, which allow everything while
+//     robots.txt is temporarily unavailable.
+func (p *Politeness) robots(host *hostState, u *url.URL) *robotsRules {
+	host.robotsMu.Lock()
+	defer host.robotsMu.Unlock()
+	if host.robots != nil {
+		return host.robots
+	}
+
+	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
+	robots, err := p.fetchRobots(robotsURL)
+	// Fetching robots.txt counts as a request to the host.
+	host.mu.Lock()
+	host.last = time.Now()
+	host.mu.Unlock()
+	if err != nil {
+		log.Printf("fetching %s without robots.txt rules: %v", u, err)
+		return &robotsRules{}
+	}
+	if robots.crawlDelay > maxCrawlDelay {
+		log.Printf("capping the crawl delay of %s at %s", robotsURL, maxCrawlDelay)
+		robots.crawlDelay = maxCrawlDelay
+	}
+	host.robots = robots
+	return robots
+}
+
+// fetchRobots fetches and parses a robots.txt file, following RFC 9309 except that
+// only 401 and 403 disallow the whole host.
+// Parameters:
+//   - robotsURL: The URL of the robots.txt file.
+//
+// Returns:
+//   - *robotsRules: The rules that apply to the User-Agent. A missing file allows everything,
+//     while a file the host refuses access to disallows everything.
+//   - error: An error if robots.txt is temporarily unavailable because of a network error,
+//     429 or a server error.
+func (p *Politeness) fetchRobots(robotsURL string) (*robotsRules, error) {
+	res, err := httpGet(p.client, robotsURL, p.userAgent, nil)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("failed to fetch %s: %w", robotsURL, err)
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("failed to fetch %s, status code: %d", robotsURL, res.StatusCode)
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return forbiddenRobots("access to %s is denied, status code: %d", robotsURL, res.StatusCode), nil
+	case res.StatusCode >= http.StatusBadRequest:
+		return &robotsRules{}, nil
+	default:
+		return parseRobots(res.Body, productToken(p.userAgent)), nil
+	}
+}
+
+// acquire takes one of the request slots of the host if one is free and delay has passed
+// since the last request to the host started, and records the start of a new request.
+// The caller must release the slot once the request is done.
+// Parameters:
+//   - delay: The minimum time between the starts of two requests to the host.
+//
+// Returns:
+//   - time.Time: The earliest time the host may be ready, if it is busy.
+//   - bool: Whether a slot was taken.
+func (h *hostState) acquire(delay time.Duration) (time.Time, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	next := h.last.Add(delay)
+	if now := time.Now(); now.Before(next) {
+		return next, false
+	}
+	select {
+	case h.slots <- struct{}{}:
+	default:
+		return time.Now().Add(max(delay, hostBusyRetry)), false
+	}
+	h.last = time.Now()
+	return time.Time{}, true
+}
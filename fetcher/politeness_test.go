@@ -0,0 +1,241 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `# robots.txt
+User-agent: *
+Disallow: /private
+Crawl-delay: 2
+
+User-agent: Googlebot
+User-agent: feed-summarizer/1.0
+Disallow: /news/drafts
+Allow: /news/drafts/public
+Disallow: /*.pdf$
+Disallow: /search*q=
+Crawl-delay: 0.5
+`
+	rules := parseRobots([]byte(robots), "Feed-Summarizer")
+	assert.Equal(t, 500*time.Millisecond, rules.crawlDelay)
+
+	tests := []struct {
+		path       string
+		disallowed string
+	}{
+		{path: "/private/page", disallowed: ""},
+		{path: "/news/drafts/1", disallowed: "Disallow: /news/drafts"},
+		{path: "/news/drafts/public/1", disallowed: ""},
+		{path: "/files/report.pdf", disallowed: "Disallow: /*.pdf$"},
+		{path: "/files/report.pdf?download=1", disallowed: ""},
+		{path: "/search?lang=ja&q=go", disallowed: "Disallow: /search*q="},
+		{path: "/robots.txt", disallowed: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule := rules.disallowedBy(tt.path)
+			if tt.disallowed == "" {
+				assert.Nil(t, rule)
+			} else if assert.NotNil(t, rule) {
+				assert.Equal(t, tt.disallowed, rule.String())
+			}
+		})
+	}
+
+	// Other crawlers fall back to the "*" group.
+	rules = parseRobots([]byte(robots), "other-bot")
+	assert.Equal(t, 2*time.Second, rules.crawlDelay)
+	assert.NotNil(t, rules.disallowedBy("/private/page"))
+	assert.Nil(t, rules.disallowedBy("/news/drafts/1"))
+
+	// A group naming the crawler without rules allows everything.
+	rules = parseRobots([]byte("User-agent: *\nDisallow: /\n\nUser-agent: feed-summarizer\nDisallow:\n"), "feed-summarizer")
+	assert.Nil(t, rules.disallowedBy("/page"))
+}
+
+func TestPoliteness(t *testing.T) {
+	const userAgent = "feed-summarizer/2.0 (+https://example.com/bot)"
+	var robotsRequests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, userAgent, r.UserAgent())
+		if r.URL.Path == "/robots.txt" {
+			robotsRequests.Add(1)
+			_, _ = w.Write([]byte("User-agent: feed-summarizer\nDisallow: /private\n"))
+			return
+		}
+		_, _ = w.Write([]byte("<p>" + r.URL.Path + "</p>"))
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var starts []time.Time
+	fetchHTML := NewHTMLPageFetcher(userAgent)
+	fetch := NewPoliteness(2, 50*time.Millisecond, userAgent).Wrap(func(url string) (string, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		time.Sleep(20 * time.Millisecond)
+		return fetchHTML(url)
+	})
+
+	urls := []string{ts.URL + "/a", ts.URL + "/b", ts.URL + "/c", ts.URL + "/d", ts.URL + "/private/e"}
+	pages, err := FetchHTMLPages(urls, fetch)
+	var disallowed *DisallowedError
+	if assert.ErrorAs(t, err, &disallowed) {
+		assert.Equal(t, ts.URL+"/private/e", disallowed.URL)
+		assert.Contains(t, disallowed.Reason, `"Disallow: /private"`)
+	}
+	assert.Len(t, pages, 4)
+	assert.Equal(t, "<p>/a</p>", pages[ts.URL+"/a"])
+	assert.Equal(t, int32(1), robotsRequests.Load(), "robots.txt should be fetched once per host")
+	assert.Equal(t, 1, maxRunning, "requests 50ms apart that take 20ms should not overlap")
+	for i := 1; i < len(starts); i++ {
+		assert.GreaterOrEqual(t, starts[i].Sub(starts[i-1]), 45*time.Millisecond)
+	}
+}
+
+func TestPoliteness_Robots(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		disallowed     bool
+		robotsRequests int32
+	}{
+		{name: "missing robots.txt allows everything", status: http.StatusNotFound, robotsRequests: 1},
+		{name: "unauthorized disallows everything", status: http.StatusUnauthorized, disallowed: true, robotsRequests: 1},
+		{name: "forbidden disallows everything", status: http.StatusForbidden, disallowed: true, robotsRequests: 1},
+		{name: "server error is retried with the next page", status: http.StatusServiceUnavailable, robotsRequests: 2},
+		{name: "rate limit is retried with the next page", status: http.StatusTooManyRequests, robotsRequests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var robotsRequests atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					robotsRequests.Add(1)
+					w.WriteHeader(tt.status)
+					return
+				}
+				_, _ = w.Write([]byte("<p>page</p>"))
+			}))
+			defer ts.Close()
+
+			fetch := NewPoliteness(1, 0, "").Wrap(FetchHTML)
+			for _, path := range []string{"/a", "/b"} {
+				page, err := fetch(ts.URL + path)
+				var disallowed *DisallowedError
+				assert.Equal(t, tt.disallowed, errors.As(err, &disallowed))
+				if !tt.disallowed {
+					assert.NoError(t, err)
+					assert.Equal(t, "<p>page</p>", page)
+				}
+			}
+			assert.Equal(t, tt.robotsRequests, robotsRequests.Load())
+		})
+	}
+
+	t.Run("network error allows the page", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("<p>page</p>"))
+		}))
+		defer ts.Close()
+
+		// A page fetcher that works while robots.txt cannot be reached, e.g. through a proxy.
+		politeness := NewPoliteness(1, 0, "")
+		politeness.client.Transport = roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		})
+		page, err := politeness.Wrap(FetchHTML)(ts.URL + "/page")
+		assert.NoError(t, err)
+		assert.Equal(t, "<p>page</p>", page)
+	})
+}
+
+// roundTripFunc is an http.RoundTripper calling a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestPoliteness_MaxPerHost(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	var running, maxRunning atomic.Int32
+	fetch := NewPoliteness(3, 0, "").Wrap(func(string) (string, error) {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return "page", nil
+	})
+
+	var urls []string
+	for _, path := range []string{"/1", "/2", "/3", "/4", "/5", "/6", "/7", "/8"} {
+		urls = append(urls, ts.URL+path)
+	}
+	pages, err := FetchHTMLPages(urls, fetch)
+	assert.NoError(t, err)
+	assert.Len(t, pages, 8)
+	assert.Equal(t, int32(3), maxRunning.Load())
+}
+
+func TestPoliteness_OtherHostsNotHeldUp(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("<p>slow</p>"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("<p>fast</p>"))
+	}))
+	defer fast.Close()
+
+	// More pages of a slow host than requests may run at once, followed by a page of another host.
+	var urls []string
+	for i := range maxConcurrentRequests + 1 {
+		urls = append(urls, fmt.Sprintf("%s/%d", slow.URL, i))
+	}
+	urls = append(urls, fast.URL+"/page")
+
+	start := time.Now()
+	var fastDone time.Duration
+	polite := NewPoliteness(1, 150*time.Millisecond, "").Wrap(FetchHTML)
+	pages, err := FetchHTMLPages(urls, func(url string) (string, error) {
+		page, err := polite(url)
+		if url == fast.URL+"/page" {
+			fastDone = time.Since(start)
+		}
+		return page, err
+	})
+	assert.NoError(t, err)
+	assert.Len(t, pages, len(urls))
+	// The page of the other host only waits for the delay after its own robots.txt.
+	assert.Less(t, fastDone, 300*time.Millisecond, "pages waiting for the delay of their host should not hold up other hosts")
+}
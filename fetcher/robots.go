@@ -0,0 +1,169 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsBytes is the size up to which a robots.txt file is parsed, as required by RFC 9309.
+const maxRobotsBytes = 500 << 10
+
+// robotsRules are the rules of a robots.txt file that apply to this crawler.
+type robotsRules struct {
+	// rules are the Allow and Disallow rules of the matching groups.
+	rules []robotsRule
+
+	// crawlDelay is the Crawl-delay of the matching groups, or 0 if they have none.
+	crawlDelay time.Duration
+
+	// forbidden is the reason the host refused access to its robots.txt file. If it is set,
+	// the whole host is disallowed.
+	forbidden string
+}
+
+// robotsRule is an Allow or Disallow line of a robots.txt file.
+type robotsRule struct {
+	// allow is true for Allow lines and false for Disallow lines.
+	allow bool
+
+	// pattern is the path pattern, which may contain "*" wildcards and end with "$".
+	pattern string
+}
+
+// String formats the rule as it appears in robots.txt.
+func (r robotsRule) String() string {
+	if r.allow {
+		return "Allow: " + r.pattern
+	}
+	return "Disallow: " + r.pattern
+}
+
+// parseRobots parses a robots.txt file and keeps the groups that apply to agent.
+// The groups naming agent are used if there are any, and the "*" groups otherwise.
+// Parameters:
+//   - body: The content of the robots.txt file.
+//   - agent: The product token of the crawler, e.g. "feed-summarizer".
+//
+// Returns:
+//   - *robotsRules: The rules and crawl delay that apply to agent.
+func parseRobots(body []byte, agent string) *robotsRules {
+	if len(body) > maxRobotsBytes {
+		body = body[:maxRobotsBytes]
+	}
+	agent = strings.ToLower(agent)
+
+	specific, wildcard := &robotsRules{}, &robotsRules{}
+	var current []*robotsRules
+	inAgents, named := false, false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64<<10), maxRobotsBytes)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				// A User-agent line after rules starts a new group.
+				current, inAgents = nil, true
+			}
+			name, _, _ := strings.Cut(strings.ToLower(value), "/")
+			switch name {
+			case agent:
+				current, named = append(current, specific), true
+			case "*":
+				current = append(current, wildcard)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				// An empty Disallow allows everything, which is the default anyway.
+				continue
+			}
+			for _, group := range current {
+				group.rules = append(group.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			inAgents = false
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				continue
+			}
+			for _, group := range current {
+				group.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	if named {
+		return specific
+	}
+	return wildcard
+}
+
+// disallowedBy returns the rule that disallows path, or nil if path is allowed.
+// The rule with the longest matching pattern applies, and Allow wins ties.
+// Parameters:
+//   - path: The escaped path and query of the URL, e.g. "/news?id=1".
+//
+// Returns:
+//   - *robotsRule: The Disallow rule applying to path, or nil.
+func (r *robotsRules) disallowedBy(path string) *robotsRule {
+	if path == "/robots.txt" {
+		return nil
+	}
+	var best *robotsRule
+	for i, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if best == nil || len(rule.pattern) > len(best.pattern) || len(rule.pattern) == len(best.pattern) && rule.allow {
+			best = &r.rules[i]
+		}
+	}
+	if best == nil || best.allow {
+		return nil
+	}
+	return best
+}
+
+// robotsMatch reports whether path matches a robots.txt path pattern. Patterns match
+// path prefixes; "*" matches any sequence of characters and a trailing "$" the end of path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	rest, ok := strings.CutPrefix(path, parts[0])
+	if !ok {
+		return false
+	}
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
+
+// forbiddenRobots returns rules disallowing a whole host that refuses access to its robots.txt.
+func forbiddenRobots(format string, args ...any) *robotsRules {
+	return &robotsRules{forbidden: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,62 @@
+package fetcher
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// blockSelector matches the elements whose text forms a paragraph of its own.
+const blockSelector = "p, li, h1, h2, h3, h4, h5, h6, blockquote, pre, td, th, dt, dd, figcaption"
+
+// nonContentSelector matches elements that never contain article text.
+const nonContentSelector = "script, style, noscript, template, svg, iframe, nav, header, footer, aside, form"
+
+// HTMLText extracts the plain text of an HTML page, one block element per line, so that
+// sentence boundaries between paragraphs and headings are kept. Scripts, styles and
+// navigation are dropped. Pages without block elements fall back to the whole body text.
+// Parameters:
+//   - page: The HTML content of the page. May be empty.
+//
+// Returns:
+//   - string: The text of the page, or an empty string if it cannot be parsed.
+func HTMLText(page string) string {
+	if strings.TrimSpace(page) == "" {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return ""
+	}
+	doc.Find(nonContentSelector).Remove()
+	return blockText(doc.Find("body"))
+}
+
+// blockText extracts the text of a selection, one block element per line.
+// Selections without block elements fall back to their whole text.
+// Parameters:
+//   - sel: The selection whose text is extracted.
+//
+// Returns:
+//   - string: The text of the selection.
+func blockText(sel *goquery.Selection) string {
+	var lines []string
+	sel.Find(blockSelector).Each(func(_ int, s *goquery.Selection) {
+		// Nested blocks, e.g. a paragraph in a list item, are taken at the innermost level.
+		if s.Find(blockSelector).Length() > 0 {
+			return
+		}
+		if line := collapseSpace(s.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	})
+	if len(lines) == 0 {
+		return collapseSpace(sel.Text())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// collapseSpace trims s and replaces each run of whitespace with a single space.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -22,7 +22,14 @@ import (
 //
 // If a single object is found, it is wrapped in an array for consistent handling.
 // This enables uniform processing of both single objects and arrays in the calling code.
+//
+// When the whole input is a JSON document, as produced by structured output, it is used
+// as-is without scanning, which also supports arbitrarily nested structures.
 func extractJSONArray(input string) ([]string, error) {
+	if result, ok := parseJSONDocument(input); ok {
+		return result, nil
+	}
+
 	// Find all JSON objects in the input
 	re := regexp.MustCompile(`(?s)(\{[^{}]*(?:\{[^{}]*\}[^{}]*)*\}|\[[^\[\]]*(?:\[[^\[\]]*\][^\[\]]*)*\])`)
 	matches := re.FindAllString(input, -1)
@@ -51,6 +58,40 @@ func extractJSONArray(input string) ([]string, error) {
 	return result, nil
 }
 
+// parseJSONDocument splits input into its elements when the whole input is a JSON array,
+// or returns it as a single element when it is a JSON object.
+// The reported bool is false if the input is not a single JSON array or object.
+func parseJSONDocument(input string) ([]string, bool) {
+	trimmed := strings.TrimSpace(input)
+	switch {
+	case strings.HasPrefix(trimmed, "["):
+		var arr []json.RawMessage
+		if err := json.Unmarshal([]byte(trimmed), &arr); err != nil {
+			return nil, false
+		}
+		result := make([]string, 0, len(arr))
+		for _, item := range arr {
+			result = append(result, string(item))
+		}
+		return result, true
+	case strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)):
+		return []string{trimmed}, true
+	default:
+		return nil, false
+	}
+}
+
+// HasJSON reports whether input contains a JSON object or array that ExtractAndFormat can extract.
+// Parameters:
+//   - input: The text to check, such as the response of a model.
+//
+// Returns:
+//   - bool: True if JSON can be extracted from input.
+func HasJSON(input string) bool {
+	_, err := extractJSONArray(input)
+	return err == nil
+}
+
 // ExtractAndFormat extracts JSON structures from the input text and formats them as a single JSON array.
 //
 // If multiple JSON objects are found in the input, they are combined into a single array.
@@ -32,6 +32,12 @@ func Test_extractJSONArray(t *testing.T) {
 			expected: nil,
 			wantErr:  true,
 		},
+		{
+			name:     "structured output with nested array",
+			input:    "[{\"heading\": \"H1\", \"tags\": [\"a\", \"b\"]}]\n",
+			expected: []string{"{\"heading\": \"H1\", \"tags\": [\"a\", \"b\"]}"},
+			wantErr:  false,
+		},
 		{
 			name:     "invalid JSON array",
 			input:    "[invalid json]",
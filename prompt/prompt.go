@@ -58,3 +58,11 @@ func (p *PromptBuilder) Append(vars any) *PromptBuilder {
 func (p *PromptBuilder) Build() string {
 	return p.SystemPrompt + p.userPrompt
 }
+
+// BuildUserPrompt returns the accumulated user input without the system prompt.
+// It is used to send the system prompt as a separate system instruction.
+// Returns:
+//   - string: The user part of the prompt.
+func (p *PromptBuilder) BuildUserPrompt() string {
+	return p.userPrompt
+}
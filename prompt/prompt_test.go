@@ -78,3 +78,10 @@ func TestPromptBuilder_Build(t *testing.T) {
 	result := builder.Build()
 	assert.Equal(t, expected, result)
 }
+
+func TestPromptBuilder_BuildUserPrompt(t *testing.T) {
+	builder := NewPromptBuilder(systemPrompt, getTemplate(templateStr))
+
+	builder.Append(map[string]string{"title": "title1", "body": "body1"})
+	assert.Equal(t, "タイトル:title1, 本文:body1\n", builder.BuildUserPrompt())
+}
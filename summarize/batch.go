@@ -0,0 +1,267 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	genAi "feed-summarizer/ai_client"
+	"feed-summarizer/usage"
+)
+
+// FeedResult is the outcome of summarizing a single feed in batch mode.
+type FeedResult struct {
+	// FeedURL is the URL of the feed.
+	FeedURL string
+
+	// Summary is the summary of the feed, or nil if it failed.
+	Summary *Summary
+
+	// Err is the error of the feed, or nil if it was summarized.
+	Err error
+
+	// Batched is true if the summary was generated by the batch job. It is false for feeds
+	// summarized again item by item with the client of the Summarizer.
+	Batched bool
+}
+
+// batchState is persisted while a batch job is running, so that an interrupted run
+// can resume waiting for the job instead of submitting the feeds again.
+type batchState struct {
+	// JobID is the ID of the batch job at the backend.
+	JobID string `json:"job_id"`
+
+	// Feeds are the feed URLs of the run, in order.
+	Feeds []string `json:"feeds"`
+
+	// Requests maps the ID of each request in the job to its feed URL.
+	// Feeds that could not be fetched have no request.
+	Requests map[string]string `json:"requests"`
+
+	// Skipped maps feed URLs to the items left out of their requests.
+	Skipped map[string][]SkippedItem `json:"skipped,omitempty"`
+
+	// SubmittedAt is the time the job was submitted.
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// SummarizeBatch summarizes the feeds with a single batch job instead of a request per feed.
+// The ID of the job is saved to statePath as soon as it is submitted. If statePath already
+// holds a job for the same feeds, e.g. because a previous run was interrupted while waiting,
+// that job is awaited instead of submitting a new one. The state file is removed once the
+// results are retrieved.
+// Feeds whose batch request is blocked or truncated are summarized again item by item with
+// the client of the Summarizer, like Summarize does.
+// Parameters:
+//   - ctx: The context of the run. Cancelling it stops waiting; the job keeps running and can be resumed.
+//   - client: The batch client to submit the job to.
+//   - feedURLs: The URLs of the RSS feeds.
+//   - statePath: The file the job state is saved to.
+//   - pollInterval: The time between polls of the job status.
+//
+// Returns:
+//   - []FeedResult: The result of each feed, in the order of feedURLs.
+//   - error: An error if the job cannot be submitted or awaited, or the state file cannot be used.
+func (s *Summarizer) SummarizeBatch(ctx context.Context, client genAi.BatchClient, feedURLs []string, statePath string, pollInterval time.Duration) ([]FeedResult, error) {
+	state, err := loadBatchState(statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]FeedResult, len(feedURLs))
+	for i, feedURL := range feedURLs {
+		results[i].FeedURL = feedURL
+	}
+
+	if state != nil {
+		if !slices.Equal(state.Feeds, feedURLs) {
+			return nil, fmt.Errorf("batch state %s belongs to a job for other feeds, remove it to submit a new job", statePath)
+		}
+		log.Printf("resuming batch job %s submitted at %s", state.JobID, state.SubmittedAt.Format(time.RFC3339))
+	} else {
+		state, err = s.submitBatch(ctx, client, feedURLs, results)
+		if err != nil {
+			return nil, err
+		}
+		if err := saveBatchState(statePath, state); err != nil {
+			return nil, fmt.Errorf("batch job %s was submitted but cannot be resumed: %w", state.JobID, err)
+		}
+		log.Printf("submitted batch job %s with %d feeds", state.JobID, len(state.Requests))
+	}
+
+	job, err := genAi.WaitBatch(ctx, client, state.JobID, pollInterval, func(job *genAi.BatchJob) {
+		log.Printf("batch job %s is %s", job.ID, job.State)
+	})
+	if err != nil {
+		if job != nil {
+			// The job is over, so there is nothing left to resume.
+			err = errors.Join(err, removeBatchState(statePath))
+		}
+		return nil, err
+	}
+
+	submitted := make(map[string]bool, len(state.Requests))
+	for _, feedURL := range state.Requests {
+		submitted[feedURL] = true
+	}
+	byFeed := make(map[string]genAi.BatchResult, len(job.Results))
+	for _, result := range job.Results {
+		if feedURL, ok := state.Requests[result.ID]; ok {
+			byFeed[feedURL] = result
+		}
+	}
+	for i := range results {
+		if results[i].Err != nil {
+			continue
+		}
+		if !submitted[results[i].FeedURL] {
+			// The feed could not be fetched when the resumed job was submitted.
+			results[i].Err = fmt.Errorf("feed %s is not part of batch job %s", results[i].FeedURL, state.JobID)
+			continue
+		}
+		result, ok := byFeed[results[i].FeedURL]
+		switch {
+		case !ok:
+			results[i].Err = fmt.Errorf("batch job %s returned no result: %w", state.JobID, genAi.ErrEmptyResponse)
+		case result.Err != nil:
+			results[i].Summary, results[i].Err = s.resummarize(ctx, results[i].FeedURL, result.Err)
+		default:
+			results[i].Summary = &Summary{Response: *result.Response, Skipped: state.Skipped[results[i].FeedURL]}
+			results[i].Batched = true
+		}
+	}
+	return results, removeBatchState(statePath)
+}
+
+// submitBatch builds the request of every feed and submits them as a batch job.
+// Parameters:
+//   - ctx: The context of the submission.
+//   - client: The batch client to submit the job to.
+//   - feedURLs: The URLs of the RSS feeds.
+//   - results: The results of the feeds, in the order of feedURLs. The error of feeds that cannot be fetched is set.
+//
+// Returns:
+//   - *batchState: The state of the submitted job.
+//   - error: An error if no feed can be fetched or the job cannot be submitted.
+func (s *Summarizer) submitBatch(ctx context.Context, client genAi.BatchClient, feedURLs []string, results []FeedResult) (*batchState, error) {
+	state := &batchState{Feeds: feedURLs, Requests: map[string]string{}, Skipped: map[string][]SkippedItem{}}
+	var requests []genAi.BatchRequest
+	for i, feedURL := range feedURLs {
+		req, _, skipped, err := s.buildRequest(feedURL)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		if len(skipped) > 0 {
+			state.Skipped[feedURL] = skipped
+		}
+		id := "feed-" + strconv.Itoa(i)
+		requests = append(requests, genAi.BatchRequest{ID: id, Request: req})
+		state.Requests[id] = feedURL
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("no feed could be fetched: %w", errors.Join(feedErrors(results)...))
+	}
+
+	jobID, err := client.SubmitBatch(ctx, requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit batch job: %w", err)
+	}
+	state.JobID = jobID
+	state.SubmittedAt = time.Now()
+	return state, nil
+}
+
+// resummarize summarizes a feed item by item after its batch request failed with batchErr.
+// Parameters:
+//   - ctx: The context of the generation requests.
+//   - feedURL: The URL of the RSS feed.
+//   - batchErr: The error of the batch request.
+//
+// Returns:
+//   - *Summary: The combined summary and the blocked items.
+//   - error: batchErr if it was not caused by the content, or an error if no item could be summarized.
+func (s *Summarizer) resummarize(ctx context.Context, feedURL string, batchErr error) (*Summary, error) {
+	if !isItemError(batchErr) {
+		return nil, batchErr
+	}
+	_, infos, skipped, err := s.buildRequest(feedURL)
+	if err != nil {
+		return nil, errors.Join(batchErr, err)
+	}
+	summary, err := s.summarizeItems(usage.WithFeed(ctx, feedURL), infos, batchErr)
+	if err != nil {
+		return nil, err
+	}
+	summary.Skipped = skipped
+	return summary, nil
+}
+
+// feedErrors returns the errors of the results.
+func feedErrors(results []FeedResult) []error {
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errs
+}
+
+// loadBatchState reads the state of a batch job.
+// Parameters:
+//   - path: The path of the state file.
+//
+// Returns:
+//   - *batchState: The saved state, or nil if the file does not exist.
+//   - error: An error if the file cannot be read or parsed.
+func loadBatchState(path string) (*batchState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch state: %w", err)
+	}
+	var state batchState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse batch state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// saveBatchState writes the state of a batch job, creating its directory if needed.
+// Parameters:
+//   - path: The path of the state file.
+//   - state: The state to save.
+//
+// Returns:
+//   - error: An error if the file cannot be written.
+func saveBatchState(path string, state *batchState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode batch state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create batch state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write batch state: %w", err)
+	}
+	return nil
+}
+
+// removeBatchState removes the state file of a finished batch job.
+func removeBatchState(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove batch state: %w", err)
+	}
+	return nil
+}
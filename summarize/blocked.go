@@ -0,0 +1,121 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	genAi "feed-summarizer/ai_client"
+)
+
+// BlockedItem is a feed item the backend refused to summarize.
+type BlockedItem struct {
+	// Title is the title of the feed item.
+	Title string `json:"title"`
+
+	// Link is the URL of the feed item.
+	Link string `json:"link"`
+
+	// Reason is the reason the backend stopped generating, e.g. genAi.FinishReasonSafety.
+	Reason genAi.FinishReason `json:"reason"`
+
+	// Detail is the reason as reported by the backend. It may be empty.
+	Detail string `json:"detail,omitempty"`
+}
+
+// isItemError reports whether err was caused by the content of the request,
+// so that summarizing the items one by one may succeed.
+func isItemError(err error) bool {
+	return errors.Is(err, genAi.ErrSafetyBlocked) ||
+		errors.Is(err, genAi.ErrRecitation) ||
+		errors.Is(err, genAi.ErrTruncated)
+}
+
+// summarizeItems summarizes the feed items one by one after the summary of the whole
+// feed failed with feedErr. Items the backend blocks are left out and reported.
+// The summaries of the other items are merged into a single JSON array when a
+// response schema is set, or joined by blank lines otherwise.
+// Parameters:
+//   - ctx: The context of the generation requests.
+//   - infos: The feed items to summarize.
+//   - feedErr: The error returned for the whole feed.
+//
+// Returns:
+//   - *Summary: The combined summary and the blocked items.
+//   - error: feedErr if it was not caused by the content, or an error if no item could be summarized.
+func (s *Summarizer) summarizeItems(ctx context.Context, infos []RSSInfo, feedErr error) (*Summary, error) {
+	if !isItemError(feedErr) || len(infos) < 2 {
+		return nil, feedErr
+	}
+
+	summary := &Summary{Response: genAi.Response{FinishReason: genAi.FinishReasonStop}}
+	var finishErr *genAi.FinishError
+	if errors.As(feedErr, &finishErr) && finishErr.Partial != nil {
+		summary.Usage = finishErr.Partial.Usage
+	}
+
+	var texts []string
+	for _, info := range infos {
+		resp, err := s.client.Send(ctx, s.newRequest([]RSSInfo{info}))
+		if err != nil {
+			if !isItemError(err) {
+				return nil, fmt.Errorf("failed to summarize %s: %w", info.Link, err)
+			}
+			blocked := BlockedItem{Title: info.Title, Link: info.Link, Reason: genAi.FinishReasonOther}
+			if errors.As(err, &finishErr) {
+				blocked.Reason = finishErr.Reason
+				blocked.Detail = finishErr.Detail
+				if finishErr.Partial != nil {
+					summary.Usage = summary.Usage.Add(finishErr.Partial.Usage)
+				}
+			}
+			summary.Blocked = append(summary.Blocked, blocked)
+			continue
+		}
+		summary.Backend = resp.Backend
+		summary.Usage = summary.Usage.Add(resp.Usage)
+		texts = append(texts, resp.Text)
+	}
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("all %d feed items were blocked: %w", len(infos), feedErr)
+	}
+
+	if s.responseSchema == nil {
+		summary.Text = strings.Join(texts, "\n\n")
+		return summary, nil
+	}
+	text, err := mergeJSONArrays(texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge item summaries: %w", err)
+	}
+	summary.Text = text
+	return summary, nil
+}
+
+// mergeJSONArrays concatenates JSON arrays into a single array.
+// Parameters:
+//   - texts: The JSON arrays.
+//
+// Returns:
+//   - string: The merged JSON array.
+//   - error: An error if one of the texts is not a JSON array.
+func mergeJSONArrays(texts []string) (string, error) {
+	merged := []json.RawMessage{}
+	for _, text := range texts {
+		var elements []json.RawMessage
+		if err := json.Unmarshal([]byte(text), &elements); err != nil {
+			return "", fmt.Errorf("response is not a JSON array: %w", err)
+		}
+		merged = append(merged, elements...)
+	}
+	// Keep the summaries readable; they are not embedded in HTML.
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(merged); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,89 @@
+package summarize
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// imageExtensions lists the file extensions of enclosures treated as images
+// when the feed does not give their media type.
+var imageExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+}
+
+// leadImageURL finds the lead image of a feed item: the item's image, else its first
+// image enclosure, else the og:image of its page. Relative URLs are resolved against
+// the item's link.
+// Parameters:
+//   - item: The feed item.
+//   - page: The HTML content of the item's page. May be empty.
+//
+// Returns:
+//   - string: The absolute URL of the image, or an empty string if there is none.
+func leadImageURL(item *gofeed.Item, page string) string {
+	var imageURL string
+	switch {
+	case item.Image != nil && item.Image.URL != "":
+		imageURL = item.Image.URL
+	case imageEnclosure(item.Enclosures) != "":
+		imageURL = imageEnclosure(item.Enclosures)
+	default:
+		imageURL = ogImage(page)
+	}
+	if imageURL == "" {
+		return ""
+	}
+
+	base, err := url.Parse(item.Link)
+	if err != nil {
+		return imageURL
+	}
+	ref, err := url.Parse(strings.TrimSpace(imageURL))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// imageEnclosure returns the URL of the first enclosure that is an image, by media type or file extension.
+func imageEnclosure(enclosures []*gofeed.Enclosure) string {
+	for _, enclosure := range enclosures {
+		if enclosure == nil || enclosure.URL == "" {
+			continue
+		}
+		if strings.HasPrefix(enclosure.Type, "image/") {
+			return enclosure.URL
+		}
+		if enclosure.Type == "" {
+			if u, err := url.Parse(enclosure.URL); err == nil && imageExtensions[strings.ToLower(path.Ext(u.Path))] {
+				return enclosure.URL
+			}
+		}
+	}
+	return ""
+}
+
+// ogImage returns the og:image of an HTML page, or an empty string if it has none.
+func ogImage(page string) string {
+	if page == "" {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return ""
+	}
+	for _, property := range []string{"og:image", "og:image:url", "og:image:secure_url"} {
+		if content := doc.Find(`meta[property="`+property+`"]`).AttrOr("content", ""); content != "" {
+			return content
+		}
+	}
+	return ""
+}
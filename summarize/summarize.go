@@ -10,18 +10,36 @@
 package summarize
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sync"
 	"text/template"
 
 	genAi "feed-summarizer/ai_client"
 	"feed-summarizer/fetcher"
 	"feed-summarizer/prompt"
+	"feed-summarizer/usage"
 
 	"github.com/mmcdole/gofeed"
 )
 
+// DefaultResponseSchema is the response shape requested from the model by default:
+// an array of {heading, summary} objects, as described in the default system prompt.
+var DefaultResponseSchema = &genAi.Schema{
+	Type: genAi.SchemaTypeArray,
+	Items: &genAi.Schema{
+		Type: genAi.SchemaTypeObject,
+		Properties: map[string]*genAi.Schema{
+			"heading": {Type: genAi.SchemaTypeString, Description: "見出し"},
+			"summary": {Type: genAi.SchemaTypeString, Description: "要約本文"},
+		},
+		Required: []string{"heading", "summary"},
+	},
+}
+
 // RSSInfo represents the title, link, and optional page content of an RSS feed item.
 type RSSInfo struct {
 	// Title is the title of the RSS feed item.
@@ -33,11 +51,27 @@ type RSSInfo struct {
 	// Page contains the optional HTML content of the RSS feed item.
 	// It is omitted from the JSON output if empty.
 	Page string `json:"page,omitempty"`
+
+	// ImageURL is the URL of the item's lead image, taken from the feed item's image,
+	// its enclosures or the og:image of its page. It is empty if the item has none.
+	ImageURL string `json:"image_url,omitempty"`
+
+	// Image is the fetched lead image, attached to the request when the Summarizer
+	// has an image fetcher. It is nil if images are not attached or could not be fetched.
+	Image *fetcher.Image `json:"-"`
+
+	// SkipReason is the reason the item is left out of the summary, e.g. because the
+	// robots.txt of its host disallows fetching its page. It is empty for summarized items.
+	SkipReason string `json:"skip_reason,omitempty"`
+
+	// html is the fetched HTML of the page, kept when Page is converted to another page content.
+	html string
 }
 
 // NewRSSInfo creates a slice of RSSInfo from a gofeed.Feed.
 // It fetches HTML content for each feed item using the provided pageFetcher.
 // If an error occurs while fetching a page, it logs the error and continues processing other items.
+// Items whose page the fetcher refuses with a *fetcher.DisallowedError get its reason as SkipReason.
 // Parameters:
 //   - feed: A pointer to a gofeed.Feed object containing RSS feed data.
 //   - pageFetcher: A function that fetches the HTML content of a given URL.
@@ -50,14 +84,27 @@ func NewRSSInfo(feed *gofeed.Feed, pageFetcher fetcher.HTMLPageFetcher) (infos [
 	for _, item := range feed.Items {
 		urls = append(urls, item.Link)
 	}
-	pages, err := fetcher.FetchHTMLPages(urls, pageFetcher)
+	var mu sync.Mutex
+	skipReasons := make(map[string]string)
+	pages, err := fetcher.FetchHTMLPages(urls, func(url string) (string, error) {
+		page, err := pageFetcher(url)
+		var disallowed *fetcher.DisallowedError
+		if errors.As(err, &disallowed) {
+			mu.Lock()
+			skipReasons[url] = disallowed.Reason
+			mu.Unlock()
+		}
+		return page, err
+	})
 
 	for _, item := range feed.Items {
 		page := pages[item.Link]
 		infos = append(infos, RSSInfo{
-			Title: item.Title,
-			Link:  item.Link,
-			Page:  page, // This value will be nil if the retrieval fails.
+			Title:      item.Title,
+			Link:       item.Link,
+			Page:       page, // This value will be nil if the retrieval fails.
+			ImageURL:   leadImageURL(item, page),
+			SkipReason: skipReasons[item.Link],
 		})
 	}
 	return infos, err
@@ -72,13 +119,18 @@ func NewRSSInfo(feed *gofeed.Feed, pageFetcher fetcher.HTMLPageFetcher) (infos [
 // 3. Builds prompts using customizable templates
 // 4. Generates summaries using AI
 type Summarizer struct {
-	client        genAi.GenAIClient
-	feedFetcher   fetcher.FeedFetcher
-	pageFetcher   fetcher.HTMLPageFetcher
-	promptBuilder *prompt.PromptBuilder
+	client         genAi.GenAIClient
+	feedFetcher    fetcher.FeedFetcher
+	pageFetcher    fetcher.HTMLPageFetcher
+	imageFetcher   fetcher.ImageFetcher
+	pageContent    fetcher.PageContent
+	promptBuilder  *prompt.PromptBuilder
+	options        genAi.GenerationOptions
+	responseSchema *genAi.Schema
 }
 
 // NewSummarizer initializes a new Summarizer instance.
+// The summarizer requests responses following DefaultResponseSchema.
 // Parameters:
 //   - client: An instance of GenAIClient for generating summaries.
 //   - feedFetcher: A function to fetch RSS feeds.
@@ -89,13 +141,44 @@ type Summarizer struct {
 func NewSummarizer(client genAi.GenAIClient, feedFetcher fetcher.FeedFetcher, pageFetcher fetcher.HTMLPageFetcher) *Summarizer {
 	promptBuilder := prompt.NewPromptBuilder(systemPrompt, userPromptTemplate)
 	return &Summarizer{
-		client:        client,
-		feedFetcher:   feedFetcher,
-		pageFetcher:   pageFetcher,
-		promptBuilder: promptBuilder,
+		client:         client,
+		feedFetcher:    feedFetcher,
+		pageFetcher:    pageFetcher,
+		pageContent:    fetcher.PageContentRaw,
+		promptBuilder:  promptBuilder,
+		responseSchema: DefaultResponseSchema,
 	}
 }
 
+// SetGenerationOptions sets the generation parameters sent with every summarization request.
+// Parameters:
+//   - options: The generation parameters. Unset fields keep the backend defaults.
+func (s *Summarizer) SetGenerationOptions(options genAi.GenerationOptions) {
+	s.options = options
+}
+
+// SetResponseSchema sets the JSON structure the model must respond with.
+// Parameters:
+//   - schema: The response schema, or nil to let the model respond in free form.
+func (s *Summarizer) SetResponseSchema(schema *genAi.Schema) {
+	s.responseSchema = schema
+}
+
+// SetImageFetcher makes the summarizer attach the lead image of each feed item to the
+// request, for backends and models that support vision.
+// Parameters:
+//   - imageFetcher: A function to fetch and downscale images, or nil to not attach images.
+func (s *Summarizer) SetImageFetcher(imageFetcher fetcher.ImageFetcher) {
+	s.imageFetcher = imageFetcher
+}
+
+// SetPageContent sets how the fetched pages are given to the model as the Page of each feed item.
+// Parameters:
+//   - mode: The raw HTML, its plain text, or the main article. The default is fetcher.PageContentRaw.
+func (s *Summarizer) SetPageContent(mode fetcher.PageContent) {
+	s.pageContent = mode
+}
+
 // LoadPromptBuilder initializes the prompt builder with system and user prompts.
 // Parameters:
 //   - sysPromptTxtPath: Path to the system prompt text file.
@@ -118,35 +201,210 @@ func (s *Summarizer) LoadPromptBuilder(sysPromptTxtPath, usrPromptTmplPath strin
 	return nil
 }
 
+// Summary is the result of summarizing a feed.
+type Summary struct {
+	genAi.Response
+
+	// Blocked lists the feed items left out of the summary because the backend
+	// refused to summarize them. It is empty when the feed was summarized at once.
+	Blocked []BlockedItem `json:"blocked,omitempty"`
+
+	// Skipped lists the feed items left out of the request, e.g. because the
+	// robots.txt of their host disallows fetching their page.
+	Skipped []SkippedItem `json:"skipped,omitempty"`
+}
+
+// SkippedItem is a feed item that was not sent to the backend.
+type SkippedItem struct {
+	// Title is the title of the feed item.
+	Title string `json:"title"`
+
+	// Link is the URL of the feed item.
+	Link string `json:"link"`
+
+	// Reason is the reason the item was skipped.
+	Reason string `json:"reason"`
+}
+
 // Summarize generates a summary for the content of the given RSS feed URL.
 // It continues processing even if some HTML pages fail to fetch, logging the errors.
+// Items whose page must not be fetched are left out and reported in the Summary.
+// If the backend blocks or truncates the summary of the whole feed, the items are
+// summarized one by one and those that are still blocked are reported in the Summary.
 // Parameters:
+//   - ctx: The context of the generation request. Cancelling it aborts the request.
+//     The requests are attributed to the feed with usage.WithFeed.
 //   - feedURL: A string representing the URL of the RSS feed.
 //
 // Returns:
-//   - string: The generated summary.
+//   - *Summary: The generated summary, the backend that produced it and the blocked and skipped items.
 //   - error: An error if the summarization process fails entirely.
-func (s *Summarizer) Summarize(feedURL string) (string, error) {
-	var err error
+func (s *Summarizer) Summarize(ctx context.Context, feedURL string) (*Summary, error) {
+	ctx = usage.WithFeed(ctx, feedURL)
+	req, infos, skipped, err := s.buildRequest(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Send(ctx, req)
+	if err != nil {
+		summary, err := s.summarizeItems(ctx, infos, err)
+		if err != nil {
+			return nil, err
+		}
+		summary.Skipped = skipped
+		return summary, nil
+	}
+	return &Summary{Response: *resp, Skipped: skipped}, nil
+}
+
+// SummarizeStream generates a summary like Summarize and passes the text to onChunk as it
+// is generated, if the client supports streaming, or at once otherwise. When the feed has
+// to be summarized item by item, the combined summary is passed to onChunk at once. A
+// request that fails after text was passed to onChunk is not summarized item by item.
+// Parameters:
+//   - ctx: The context of the generation request. Cancelling it aborts the request.
+//     The requests are attributed to the feed with usage.WithFeed.
+//   - feedURL: A string representing the URL of the RSS feed.
+//   - onChunk: Called with each piece of the summary in order. Returning an error aborts the generation.
+//
+// Returns:
+//   - *Summary: The whole summary, the backend that produced it and the blocked and skipped items.
+//   - error: An error if the summarization process fails entirely.
+func (s *Summarizer) SummarizeStream(ctx context.Context, feedURL string, onChunk func(string) error) (*Summary, error) {
+	ctx = usage.WithFeed(ctx, feedURL)
+	req, infos, skipped, err := s.buildRequest(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	emitted := false
+	var chunkErr error
+	resp, err := genAi.SendStream(ctx, s.client, req, func(chunk string) error {
+		if chunk != "" {
+			emitted = true
+		}
+		chunkErr = onChunk(chunk)
+		return chunkErr
+	})
+	if err == nil {
+		return &Summary{Response: *resp, Skipped: skipped}, nil
+	}
+	if emitted || chunkErr != nil {
+		// The text passed to onChunk cannot be taken back, so summarizing the items
+		// again would output them twice.
+		return nil, err
+	}
+
+	summary, err := s.summarizeItems(ctx, infos, err)
+	if err != nil {
+		return nil, err
+	}
+	summary.Skipped = skipped
+	if err := onChunk(summary.Text); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// buildRequest fetches the feed and its pages and builds the summarization request.
+// Items with a SkipReason are left out of the request.
+// Parameters:
+//   - feedURL: A string representing the URL of the RSS feed.
+//
+// Returns:
+//   - genAi.Request: The request with the system prompt, the feed content and the response schema.
+//   - []RSSInfo: The feed items included in the request.
+//   - []SkippedItem: The feed items left out of the request.
+//   - error: An error if the prompt builder is missing, the feed cannot be fetched or every item is skipped.
+func (s *Summarizer) buildRequest(feedURL string) (genAi.Request, []RSSInfo, []SkippedItem, error) {
 	if s.promptBuilder == nil {
-		return "", fmt.Errorf("prompt builder is not initialized")
+		return genAi.Request{}, nil, nil, fmt.Errorf("prompt builder is not initialized")
 	}
 
 	feed, err := s.feedFetcher(feedURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch RSS feed: %w", err)
+		return genAi.Request{}, nil, nil, fmt.Errorf("failed to fetch RSS feed: %w", err)
 	}
 
-	infos, err := NewRSSInfo(feed, s.pageFetcher)
+	all, err := NewRSSInfo(feed, s.pageFetcher)
 	if err != nil {
 		log.Printf("failed to fetch HTML for some URLs: %v", err) // Continue if page retrieval fails
 	}
+	var infos []RSSInfo
+	var skipped []SkippedItem
+	for _, info := range all {
+		if info.SkipReason != "" {
+			skipped = append(skipped, SkippedItem{Title: info.Title, Link: info.Link, Reason: info.SkipReason})
+			continue
+		}
+		infos = append(infos, info)
+	}
+	if len(infos) == 0 && len(skipped) > 0 {
+		return genAi.Request{}, nil, nil, fmt.Errorf("all %d feed items were skipped, e.g. %s: %s", len(skipped), skipped[0].Link, skipped[0].Reason)
+	}
+	if s.imageFetcher != nil {
+		s.attachImages(infos)
+	}
+	if s.pageContent != fetcher.PageContentRaw {
+		for i := range infos {
+			infos[i].html = infos[i].Page
+			infos[i].Page = fetcher.ConvertPage(infos[i].Page, infos[i].Link, s.pageContent)
+		}
+	}
+	return s.newRequest(infos), infos, skipped, nil
+}
 
+// attachImages fetches the lead images of the feed items and sets their Image.
+// Items whose image cannot be fetched are summarized without it.
+// Parameters:
+//   - infos: The feed items.
+func (s *Summarizer) attachImages(infos []RSSInfo) {
+	var urls []string
 	for _, info := range infos {
-		s.promptBuilder.Append(info)
+		if info.ImageURL != "" {
+			urls = append(urls, info.ImageURL)
+		}
 	}
+	images, err := fetcher.FetchImages(urls, s.imageFetcher)
+	if err != nil {
+		log.Printf("failed to fetch some images: %v", err) // Continue without the images
+	}
+	for i := range infos {
+		infos[i].Image = images[infos[i].ImageURL]
+	}
+}
 
-	return s.client.Send(s.promptBuilder.Build())
+// newRequest builds a summarization request for the given feed items.
+// A new prompt builder is used for every request so items of other feeds are not included.
+// The images of the items are attached to the user message in the order of the items,
+// and the items are also given as plain text documents for the extractive backend.
+// The response is expected to contain JSON even without a response schema, as it is
+// parsed with the jsonify package.
+// Parameters:
+//   - infos: The feed items to summarize.
+//
+// Returns:
+//   - genAi.Request: The request with the system prompt, the items and the response schema.
+func (s *Summarizer) newRequest(infos []RSSInfo) genAi.Request {
+	builder := prompt.NewPromptBuilder(s.promptBuilder.SystemPrompt, s.promptBuilder.UserPromptTemplate)
+	for _, info := range infos {
+		builder.Append(info)
+	}
+
+	// The system prompt is sent as a system instruction, separately from the feed content.
+	req := genAi.NewUserRequest(builder.SystemPrompt, builder.BuildUserPrompt(), s.options)
+	for _, info := range infos {
+		if info.Image != nil {
+			req.Messages[0].Images = append(req.Messages[0].Images, genAi.Image{MIMEType: info.Image.MIMEType, Data: info.Image.Data})
+		}
+		html := info.Page
+		if info.html != "" {
+			html = info.html
+		}
+		req.Documents = append(req.Documents, genAi.Document{Title: info.Title, Link: info.Link, Text: fetcher.HTMLText(html)})
+	}
+	req.ResponseSchema = s.responseSchema
+	req.ExpectJSON = true
+	return req
 }
 
 // txtFileLoader reads the content of a text file and returns it as a string.
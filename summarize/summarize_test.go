@@ -1,29 +1,40 @@
 package summarize
 
 import (
+	"context"
 	"errors"
+	genAi "feed-summarizer/ai_client"
 	"feed-summarizer/fetcher"
+	"feed-summarizer/jsonify"
 	"feed-summarizer/prompt"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/mmcdole/gofeed"
 	"github.com/stretchr/testify/assert"
 )
 
-type MockGenAIClient struct{}
+type MockGenAIClient struct {
+	lastRequest genAi.Request
+}
 
-func (m *MockGenAIClient) Send(prompt string) (string, error) {
-	if prompt == "error" {
-		return "", errors.New("mock error")
+func (m *MockGenAIClient) Send(_ context.Context, req genAi.Request) (*genAi.Response, error) {
+	m.lastRequest = req
+	if len(req.Messages) > 0 && req.Messages[0].Text == "error" {
+		return nil, errors.New("mock error")
 	}
-	return "mock summary", nil
+	return &genAi.Response{Text: "mock summary", Backend: "mock:model"}, nil
 }
 
 var testSystemPrompt = `あなたはニュース記事やブログ記事を短く正確にまとめる要約アシスタントです。
@@ -55,9 +66,9 @@ func TestSummarize_Updated(t *testing.T) {
 	// テンプレートの直接設定
 	s.promptBuilder = prompt.NewPromptBuilder(testSystemPrompt, template.Must(template.New("user").Parse(testUserPromptTemplate)))
 
-	result, err := s.Summarize("http://example.com/rss")
+	result, err := s.Summarize(context.Background(), "http://example.com/rss")
 	assert.NoError(t, err, "Summarize returned an unexpected error")
-	assert.Equal(t, "mock summary", result, "Summarize result mismatch")
+	assert.Equal(t, "mock summary", result.Text, "Summarize result mismatch")
 }
 
 func TestFetchFeed(t *testing.T) {
@@ -176,7 +187,518 @@ func TestSummarize_ErrorHandling(t *testing.T) {
 
 	s := NewSummarizer(mockClient, mockFeedFetcher, mockPageFetcher)
 	_ = s.LoadPromptBuilder("../../templates/system_prompt.txt", "../../templates/user_prompt.tmpl")
-	result, err := s.Summarize("http://example.com/rss")
+	result, err := s.Summarize(context.Background(), "http://example.com/rss")
 	assert.NoError(t, err, "Summarize returned an unexpected error")
-	assert.Equal(t, "mock summary", result, "Summarize result mismatch")
+	assert.Equal(t, "mock summary", result.Text, "Summarize result mismatch")
+}
+
+func TestSummarize_Request(t *testing.T) {
+	mockClient := &MockGenAIClient{}
+	mockFeedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{
+			Items: []*gofeed.Item{
+				{Title: "Test Item", Link: "http://example.com/test"},
+			},
+		}, nil
+	}
+	mockPageFetcher := func(_ string) (string, error) {
+		return "", nil
+	}
+
+	s := NewSummarizer(mockClient, mockFeedFetcher, mockPageFetcher)
+	s.promptBuilder = prompt.NewPromptBuilder(testSystemPrompt, template.Must(template.New("user").Parse(testUserPromptTemplate)))
+	temperature := float32(0.1)
+	s.SetGenerationOptions(genAi.GenerationOptions{Temperature: &temperature})
+
+	_, err := s.Summarize(context.Background(), "http://example.com/rss")
+	assert.NoError(t, err)
+
+	req := mockClient.lastRequest
+	assert.Equal(t, testSystemPrompt, req.SystemInstruction)
+	assert.Len(t, req.Messages, 1)
+	assert.Equal(t, genAi.RoleUser, req.Messages[0].Role)
+	assert.Contains(t, req.Messages[0].Text, "Test Item")
+	assert.NotContains(t, req.Messages[0].Text, testSystemPrompt)
+	assert.Equal(t, &temperature, req.Options.Temperature)
+	assert.Same(t, DefaultResponseSchema, req.ResponseSchema)
+
+	s.SetResponseSchema(nil)
+	_, err = s.Summarize(context.Background(), "http://example.com/rss")
+	assert.NoError(t, err)
+	assert.Nil(t, mockClient.lastRequest.ResponseSchema)
+}
+
+func TestSummarize_FakeGeminiServer(t *testing.T) {
+	gemini := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasSuffix(r.URL.Path, ":generateContent"), "unexpected path %s", r.URL.Path)
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "Test Item")
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"[{\"heading\":\"h\",\"summary\":\"s\"}]"}]},"finishReason":"STOP"}]}`))
+		assert.NoError(t, err)
+	}))
+	defer gemini.Close()
+
+	t.Setenv("GEMINI_API_KEY", "test-key")
+	client, err := genAi.NewGenAIClient(context.Background(), "gemini", genAi.Config{GeminiBaseURL: gemini.URL})
+	assert.NoError(t, err)
+
+	mockFeedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{
+			Items: []*gofeed.Item{
+				{Title: "Test Item", Link: "http://example.com/test"},
+			},
+		}, nil
+	}
+	mockPageFetcher := func(_ string) (string, error) {
+		return "<html>Test Page</html>", nil
+	}
+
+	s := NewSummarizer(client, mockFeedFetcher, mockPageFetcher)
+	result, err := s.Summarize(context.Background(), "http://example.com/rss")
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"heading":"h","summary":"s"}]`, result.Text)
+}
+
+// recordCassette re-records testdata/summarize_cassette.json against the backend selected
+// by GEN_API_KIND (default "gemini"), e.g. `go test ./summarize -run Cassette -record`.
+var recordCassette = flag.Bool("record", false, "record the summarize cassette against a real backend")
+
+// cassetteFeed is the feed whose summarization is recorded in testdata/summarize_cassette.json.
+var cassetteFeed = &gofeed.Feed{
+	Items: []*gofeed.Item{
+		{Title: "Go 1.25 がリリースされました", Link: "https://example.com/go-1-25"},
+		{Title: "東京で記録的な大雨、交通機関に乱れ", Link: "https://example.com/tokyo-rain"},
+	},
+}
+
+// cassettePages are the pages of the items of cassetteFeed.
+var cassettePages = map[string]string{
+	"https://example.com/go-1-25":    "<html><body><h1>Go 1.25 リリース</h1><p>Go チームは 8 月 12 日、Go 1.25 を公開した。コンテナ環境で GOMAXPROCS を自動調整するほか、実験的なガベージコレクタ Green Tea を導入した。</p></body></html>",
+	"https://example.com/tokyo-rain": "<html><body><h1>東京で記録的大雨</h1><p>気象庁によると、11 日夜に東京都心で 1 時間に 80 ミリの猛烈な雨を観測した。JR 山手線など 12 路線で運転を見合わせた。</p></body></html>",
+}
+
+func TestSummarize_Cassette(t *testing.T) {
+	path := filepath.Join("testdata", "summarize_cassette.json")
+	var client genAi.GenAIClient
+	var err error
+	if *recordCassette {
+		kind := os.Getenv("GEN_API_KIND")
+		if kind == "" {
+			kind = "gemini"
+		}
+		backend, backendErr := genAi.NewGenAIClient(context.Background(), kind, genAi.Config{})
+		assert.NoError(t, backendErr)
+		client, err = genAi.NewCassetteClient(path, genAi.CassetteRecord, backend)
+	} else {
+		client, err = genAi.NewCassetteClient(path, genAi.CassetteReplay, nil)
+	}
+	assert.NoError(t, err)
+
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return cassetteFeed, nil
+	}
+	pageFetcher := func(url string) (string, error) {
+		return cassettePages[url], nil
+	}
+
+	// The replay only matches while the default prompts, schema and feed are unchanged.
+	// Re-record the cassette after changing any of them.
+	s := NewSummarizer(client, feedFetcher, pageFetcher)
+	result, err := s.Summarize(context.Background(), "https://example.com/rss")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	formatted, err := jsonify.ExtractAndFormat(result.Text, jsonify.OutputTemplate)
+	assert.NoError(t, err)
+	assert.Len(t, formatted, len(cassetteFeed.Items))
+	for _, item := range formatted {
+		entity, ok := item.(map[string]any)
+		assert.True(t, ok)
+		assert.NotEmpty(t, entity["heading"])
+		assert.NotEmpty(t, entity["summary"])
+	}
+}
+
+func TestSummarizeStream(t *testing.T) {
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{Items: []*gofeed.Item{{Title: "Test Item", Link: "http://example.com/test"}}}, nil
+	}
+	pageFetcher := func(_ string) (string, error) {
+		return "<html>Test Page</html>", nil
+	}
+
+	var chunks []string
+	s := NewSummarizer(&MockGenAIClient{}, feedFetcher, pageFetcher)
+	result, err := s.SummarizeStream(context.Background(), "http://example.com/rss", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "mock summary", result.Text)
+	assert.Equal(t, []string{"mock summary"}, chunks, "a client without streaming should deliver the summary at once")
+}
+
+// failingStreamClient streams its text and then fails with err.
+type failingStreamClient struct {
+	text     string
+	err      error
+	requests int
+}
+
+func (c *failingStreamClient) Send(_ context.Context, _ genAi.Request) (*genAi.Response, error) {
+	c.requests++
+	return nil, c.err
+}
+
+func (c *failingStreamClient) SendStream(_ context.Context, _ genAi.Request, onChunk func(string) error) (*genAi.Response, error) {
+	c.requests++
+	if err := onChunk(c.text); err != nil {
+		return nil, err
+	}
+	return nil, c.err
+}
+
+func TestSummarizeStream_FailsAfterChunks(t *testing.T) {
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "Item A", Link: "http://example.com/a"},
+			{Title: "Item B", Link: "http://example.com/b"},
+		}}, nil
+	}
+	pageFetcher := func(_ string) (string, error) { return "", nil }
+	blocked := &genAi.FinishError{Backend: "mock API", Reason: genAi.FinishReasonSafety}
+
+	client := &failingStreamClient{text: `[{"heading":"Item A",`, err: blocked}
+	s := NewSummarizer(client, feedFetcher, pageFetcher)
+	var chunks []string
+	_, err := s.SummarizeStream(context.Background(), "http://example.com/rss", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.ErrorIs(t, err, genAi.ErrSafetyBlocked)
+	assert.Equal(t, []string{`[{"heading":"Item A",`}, chunks, "the emitted text must not be followed by a second summary")
+	assert.Equal(t, 1, client.requests, "the items must not be summarized again")
+
+	// Without emitted text, the feed is still summarized item by item.
+	client = &failingStreamClient{err: blocked}
+	s = NewSummarizer(client, feedFetcher, pageFetcher)
+	_, err = s.SummarizeStream(context.Background(), "http://example.com/rss", func(string) error { return nil })
+	assert.Error(t, err)
+	assert.Equal(t, 3, client.requests)
+}
+
+// blockingClient blocks requests that mention one of its keywords.
+type blockingClient struct {
+	blocked  []string
+	requests int
+}
+
+func (c *blockingClient) Send(_ context.Context, req genAi.Request) (*genAi.Response, error) {
+	c.requests++
+	text := req.Messages[0].Text
+	for _, keyword := range c.blocked {
+		if strings.Contains(text, keyword) {
+			return nil, &genAi.FinishError{Backend: "mock API", Reason: genAi.FinishReasonSafety, Detail: "PROHIBITED_CONTENT"}
+		}
+	}
+	title := text[strings.Index(text, "Item"):strings.Index(text, ",")]
+	return &genAi.Response{
+		Text:    fmt.Sprintf(`[{"heading":%q,"summary":"<s>"}]`, title),
+		Backend: "mock:model",
+		Usage:   genAi.Usage{PromptTokens: 10, OutputTokens: 5},
+	}, nil
+}
+
+func TestSummarize_BlockedItems(t *testing.T) {
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "Item A", Link: "http://example.com/a"},
+			{Title: "Item B", Link: "http://example.com/b"},
+			{Title: "Item C", Link: "http://example.com/c"},
+		}}, nil
+	}
+	pageFetcher := func(_ string) (string, error) { return "", nil }
+
+	client := &blockingClient{blocked: []string{"Item B"}}
+	s := NewSummarizer(client, feedFetcher, pageFetcher)
+	s.promptBuilder = prompt.NewPromptBuilder(testSystemPrompt, template.Must(template.New("user").Parse(testUserPromptTemplate)))
+
+	result, err := s.Summarize(context.Background(), "http://example.com/rss")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, client.requests, "the feed should be retried item by item")
+	assert.JSONEq(t, `[{"heading":"Item A","summary":"<s>"},{"heading":"Item C","summary":"<s>"}]`, result.Text)
+	assert.Contains(t, result.Text, "<s>", "summaries should not be HTML escaped")
+	assert.Equal(t, "mock:model", result.Backend)
+	assert.Equal(t, genAi.Usage{PromptTokens: 20, OutputTokens: 10}, result.Usage)
+	assert.Equal(t, []BlockedItem{{
+		Title:  "Item B",
+		Link:   "http://example.com/b",
+		Reason: genAi.FinishReasonSafety,
+		Detail: "PROHIBITED_CONTENT",
+	}}, result.Blocked)
+
+	// The items of every feed are summarized on their own.
+	client.requests = 0
+	_, err = s.SummarizeStream(context.Background(), "http://example.com/rss", func(string) error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 4, client.requests)
+
+	client.blocked = []string{"Item"}
+	_, err = s.Summarize(context.Background(), "http://example.com/rss")
+	assert.ErrorIs(t, err, genAi.ErrSafetyBlocked)
+	assert.Contains(t, err.Error(), "all 3 feed items were blocked")
+}
+
+func TestSummarize_SkippedItems(t *testing.T) {
+	mockClient := &MockGenAIClient{}
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "Public Item", Link: "http://example.com/public"},
+			{Title: "Private Item", Link: "http://example.com/private"},
+		}}, nil
+	}
+	reason := `robots.txt of example.com has "Disallow: /private"`
+	pageFetcher := func(url string) (string, error) {
+		if url == "http://example.com/private" {
+			return "", &fetcher.DisallowedError{URL: url, Reason: reason}
+		}
+		return "<p>Public page</p>", nil
+	}
+
+	s := NewSummarizer(mockClient, feedFetcher, pageFetcher)
+	s.promptBuilder = prompt.NewPromptBuilder(testSystemPrompt, template.Must(template.New("user").Parse(testUserPromptTemplate)))
+	result, err := s.Summarize(context.Background(), "http://example.com/rss")
+	assert.NoError(t, err)
+	assert.Equal(t, []SkippedItem{{Title: "Private Item", Link: "http://example.com/private", Reason: reason}}, result.Skipped)
+	assert.Contains(t, mockClient.lastRequest.Messages[0].Text, "Public Item")
+	assert.NotContains(t, mockClient.lastRequest.Messages[0].Text, "Private Item")
+	assert.Len(t, mockClient.lastRequest.Documents, 1)
+
+	infos, err := NewRSSInfo(&gofeed.Feed{Items: []*gofeed.Item{{Title: "Private Item", Link: "http://example.com/private"}}}, pageFetcher)
+	assert.Error(t, err)
+	assert.Equal(t, reason, infos[0].SkipReason)
+
+	pageFetcher = func(url string) (string, error) {
+		return "", &fetcher.DisallowedError{URL: url, Reason: reason}
+	}
+	s = NewSummarizer(mockClient, feedFetcher, pageFetcher)
+	_, err = s.Summarize(context.Background(), "http://example.com/rss")
+	assert.ErrorContains(t, err, "all 2 feed items were skipped")
+}
+
+func TestLeadImageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		item *gofeed.Item
+		page string
+		want string
+	}{
+		{
+			name: "item image",
+			item: &gofeed.Item{Link: "https://example.com/posts/1", Image: &gofeed.Image{URL: "https://cdn.example.com/a.png"}},
+			page: `<meta property="og:image" content="https://example.com/og.png">`,
+			want: "https://cdn.example.com/a.png",
+		},
+		{
+			name: "image enclosure",
+			item: &gofeed.Item{Link: "https://example.com/posts/1", Enclosures: []*gofeed.Enclosure{
+				{URL: "https://example.com/episode.mp3", Type: "audio/mpeg"},
+				{URL: "https://example.com/cover.JPG"},
+			}},
+			want: "https://example.com/cover.JPG",
+		},
+		{
+			name: "relative og:image",
+			item: &gofeed.Item{Link: "https://example.com/posts/1"},
+			page: `<html><head><meta property="og:image" content="/images/launch.png"></head></html>`,
+			want: "https://example.com/images/launch.png",
+		},
+		{
+			name: "no image",
+			item: &gofeed.Item{Link: "https://example.com/posts/1"},
+			page: "<html><body>text</body></html>",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, leadImageURL(tt.item, tt.page))
+		})
+	}
+}
+
+func TestSummarize_Images(t *testing.T) {
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "Launch", Link: "http://example.com/launch", Image: &gofeed.Image{URL: "http://example.com/launch.png"}},
+			{Title: "Text only", Link: "http://example.com/text"},
+			{Title: "Broken", Link: "http://example.com/broken", Image: &gofeed.Image{URL: "http://example.com/broken.png"}},
+		}}, nil
+	}
+	pageFetcher := func(_ string) (string, error) { return "", nil }
+	imageFetcher := func(url string) (*fetcher.Image, error) {
+		if strings.Contains(url, "broken") {
+			return nil, errors.New("not found")
+		}
+		return &fetcher.Image{MIMEType: "image/png", Data: []byte(url)}, nil
+	}
+
+	mockClient := &MockGenAIClient{}
+	s := NewSummarizer(mockClient, feedFetcher, pageFetcher)
+	_, err := s.Summarize(context.Background(), "http://example.com/rss")
+	assert.NoError(t, err)
+	assert.Empty(t, mockClient.lastRequest.Messages[0].Images, "images are only attached with an image fetcher")
+
+	s.SetImageFetcher(imageFetcher)
+	_, err = s.Summarize(context.Background(), "http://example.com/rss")
+	assert.NoError(t, err)
+	message := mockClient.lastRequest.Messages[0]
+	assert.Equal(t, []genAi.Image{{MIMEType: "image/png", Data: []byte("http://example.com/launch.png")}}, message.Images)
+	assert.Contains(t, message.Text, "添付画像: http://example.com/launch.png")
+	assert.NotContains(t, message.Text, "broken.png")
+}
+
+func TestSummarize_PageContent(t *testing.T) {
+	mockClient := &MockGenAIClient{}
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return &gofeed.Feed{Items: []*gofeed.Item{{Title: "Test Item", Link: "http://example.com/test"}}}, nil
+	}
+	pageFetcher := func(_ string) (string, error) {
+		return `<html><head><meta property="og:image" content="/lead.png"><meta name="author" content="Jane Doe"></head>
+			<body><nav><a href="/">Home</a></nav><p>Article text.</p></body></html>`, nil
+	}
+
+	s := NewSummarizer(mockClient, feedFetcher, pageFetcher)
+	s.promptBuilder = prompt.NewPromptBuilder(testSystemPrompt, template.Must(template.New("user").Parse(testUserPromptTemplate)))
+	s.SetPageContent(fetcher.PageContentArticle)
+
+	_, infos, _, err := s.buildRequest("http://example.com/rss")
+	assert.NoError(t, err)
+	assert.Equal(t, "Author: Jane Doe\n\nArticle text.", infos[0].Page)
+	assert.Equal(t, "http://example.com/lead.png", infos[0].ImageURL, "the image should be found in the fetched HTML")
+
+	req := s.newRequest(infos)
+	assert.Contains(t, req.Messages[0].Text, "Author: Jane Doe")
+	assert.NotContains(t, req.Messages[0].Text, "<p>")
+	assert.Equal(t, "Article text.", req.Documents[0].Text, "documents should be extracted from the fetched HTML")
+}
+
+func TestSummarize_Extractive(t *testing.T) {
+	feedFetcher := func(_ string) (*gofeed.Feed, error) {
+		return cassetteFeed, nil
+	}
+	pageFetcher := func(url string) (string, error) {
+		return cassettePages[url], nil
+	}
+
+	s := NewSummarizer(genAi.NewExtractiveClient(1), feedFetcher, pageFetcher)
+	result, err := s.Summarize(context.Background(), "https://example.com/rss")
+	assert.NoError(t, err)
+	assert.Equal(t, "extractive:textrank", result.Backend)
+
+	formatted, err := jsonify.ExtractAndFormat(result.Text, jsonify.OutputTemplate)
+	assert.NoError(t, err)
+	assert.Len(t, formatted, len(cassetteFeed.Items))
+	for i, item := range formatted {
+		entity := item.(map[string]any)
+		assert.Equal(t, cassetteFeed.Items[i].Title, entity["heading"])
+		assert.NotEmpty(t, entity["summary"])
+	}
+}
+
+// fakeBatchClient runs batch jobs in memory. Jobs keep running until done is set,
+// calling onRunning on each poll, and then summarize each request with its first document title, or block it if the
+// title mentions one of the blocked keywords.
+type fakeBatchClient struct {
+	requests  []genAi.BatchRequest
+	blocked   []string
+	done      bool
+	onRunning func()
+}
+
+func (f *fakeBatchClient) SubmitBatch(_ context.Context, requests []genAi.BatchRequest) (string, error) {
+	f.requests = requests
+	return "batches/1", nil
+}
+
+func (f *fakeBatchClient) GetBatch(_ context.Context, id string) (*genAi.BatchJob, error) {
+	if !f.done {
+		if f.onRunning != nil {
+			f.onRunning()
+		}
+		return &genAi.BatchJob{ID: id, State: genAi.BatchRunning}, nil
+	}
+	job := &genAi.BatchJob{ID: id, State: genAi.BatchSucceeded}
+	for _, r := range f.requests {
+		result := genAi.BatchResult{ID: r.ID}
+		title := r.Request.Documents[0].Title
+		if slices.ContainsFunc(f.blocked, func(keyword string) bool { return strings.Contains(title, keyword) }) {
+			result.Err = &genAi.FinishError{Backend: "mock API", Reason: genAi.FinishReasonSafety}
+		} else {
+			result.Response = &genAi.Response{Text: "summary of " + title, Backend: "mock-batch:model"}
+		}
+		job.Results = append(job.Results, result)
+	}
+	return job, nil
+}
+
+func TestSummarizeBatch(t *testing.T) {
+	feedFetcher := func(feedURL string) (*gofeed.Feed, error) {
+		if strings.Contains(feedURL, "broken") {
+			return nil, errors.New("not found")
+		}
+		name := strings.TrimPrefix(feedURL, "http://example.com/")
+		return &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "Item " + name + " 1", Link: feedURL + "/1"},
+			{Title: "Item " + name + " 2", Link: feedURL + "/2"},
+		}}, nil
+	}
+	pageFetcher := func(_ string) (string, error) { return "", nil }
+	feeds := []string{"http://example.com/a", "http://example.com/broken", "http://example.com/b"}
+	statePath := filepath.Join(t.TempDir(), "state", "batch.json")
+
+	client := &blockingClient{blocked: []string{"Item b 1"}}
+	s := NewSummarizer(client, feedFetcher, pageFetcher)
+	s.promptBuilder = prompt.NewPromptBuilder(testSystemPrompt, template.Must(template.New("user").Parse(testUserPromptTemplate)))
+	// The run is interrupted while the job is running, and the job is saved for resuming.
+	ctx, cancel := context.WithCancel(context.Background())
+	batch := &fakeBatchClient{blocked: []string{"Item b"}, onRunning: cancel}
+	_, err := s.SummarizeBatch(ctx, batch, feeds, statePath, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+	if !assert.Len(t, batch.requests, 2, "feeds that cannot be fetched should not be submitted") {
+		return
+	}
+	assert.FileExists(t, statePath)
+
+	// Other feeds cannot resume the job.
+	_, err = s.SummarizeBatch(context.Background(), batch, feeds[:1], statePath, time.Millisecond)
+	assert.ErrorContains(t, err, "belongs to a job for other feeds")
+
+	batch.done = true
+	submitted := batch.requests
+	results, err := s.SummarizeBatch(context.Background(), batch, feeds, statePath, time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, submitted, batch.requests, "the job should be resumed instead of submitted again")
+	assert.NoFileExists(t, statePath)
+	if !assert.Len(t, results, 3) {
+		return
+	}
+
+	assert.Equal(t, "http://example.com/a", results[0].FeedURL)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "summary of Item a 1", results[0].Summary.Text)
+	assert.Equal(t, "mock-batch:model", results[0].Summary.Backend)
+
+	assert.Equal(t, "http://example.com/broken", results[1].FeedURL)
+	assert.ErrorContains(t, results[1].Err, "is not part of batch job batches/1")
+
+	// The blocked feed is summarized item by item with the synchronous client.
+	assert.NoError(t, results[2].Err)
+	assert.JSONEq(t, `[{"heading":"Item b 2","summary":"<s>"}]`, results[2].Summary.Text)
+	assert.Len(t, results[2].Summary.Blocked, 1)
 }
@@ -0,0 +1,241 @@
+// Package usage aggregates the token usage of AI requests and estimates their cost.
+// It includes the Tracker type, which sums usage per feed and per run, and the
+// PriceTable type, which holds the per-token prices of each backend.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+
+	genAi "feed-summarizer/ai_client"
+)
+
+// Price is the price of a backend in US dollars per million tokens.
+type Price struct {
+	// Input is the price of prompt tokens.
+	Input float64 `json:"input"`
+
+	// Output is the price of generated tokens.
+	Output float64 `json:"output"`
+
+	// CachedInput is the price of prompt tokens served from the backend's prompt cache.
+	// When zero, cached tokens are charged at the Input price.
+	CachedInput float64 `json:"cached_input,omitempty"`
+}
+
+// PriceTable maps a backend, as reported in genAi.Response.Backend, e.g.
+// "gemini:gemini-2.5-flash-lite", to its price.
+type PriceTable map[string]Price
+
+// LoadPriceTable reads a price table from a JSON file.
+// Parameters:
+//   - path: The path to a JSON object mapping backends to prices.
+//
+// Returns:
+//   - PriceTable: The loaded price table.
+//   - error: An error if the file cannot be read or parsed.
+func LoadPriceTable(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price table %s: %w", path, err)
+	}
+	var prices PriceTable
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("failed to parse price table %s: %w", path, err)
+	}
+	return prices, nil
+}
+
+// Cost estimates the cost of the usage of a backend in US dollars.
+// Parameters:
+//   - backend: The backend that consumed the tokens.
+//   - u: The token usage.
+//
+// Returns:
+//   - float64: The estimated cost.
+//   - bool: false if the backend has no price in the table.
+func (p PriceTable) Cost(backend string, u genAi.Usage) (float64, bool) {
+	price, ok := p[backend]
+	if !ok {
+		return 0, false
+	}
+	cachedPrice := price.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = price.Input
+	}
+	uncached := u.PromptTokens - u.CachedTokens
+	cost := float64(uncached)*price.Input + float64(u.CachedTokens)*cachedPrice + float64(u.OutputTokens)*price.Output
+	return cost / 1e6, true
+}
+
+// FeedUsage is the usage of a single backend while summarizing a feed.
+type FeedUsage struct {
+	// Feed is the URL of the feed.
+	Feed string `json:"feed"`
+
+	// Backend is the backend that consumed the tokens.
+	Backend string `json:"backend"`
+
+	// Requests is the number of requests.
+	Requests int `json:"requests"`
+
+	// Usage is the total token usage.
+	Usage genAi.Usage `json:"usage"`
+
+	// Cost is the estimated cost in US dollars, or nil if the backend has no price.
+	Cost *float64 `json:"cost_usd"`
+}
+
+// Report is the usage of a run.
+type Report struct {
+	// Feeds holds the usage per feed and backend in the order the feeds were summarized.
+	Feeds []FeedUsage `json:"feeds"`
+
+	// Total is the total token usage of the run.
+	Total genAi.Usage `json:"total"`
+
+	// Cost is the estimated cost of the run in US dollars, excluding backends without a price.
+	Cost float64 `json:"cost_usd"`
+
+	// Unpriced lists the backends without a price, whose cost is not included.
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// Tracker aggregates the usage of a run. It is safe for concurrent use.
+type Tracker struct {
+	// prices is used to estimate costs.
+	prices PriceTable
+
+	// mu guards feeds.
+	mu sync.Mutex
+
+	// feeds holds the usage per feed and backend in the order they were first seen.
+	feeds []FeedUsage
+}
+
+// NewTracker creates a new instance of Tracker.
+// Parameters:
+//   - prices: The price table used to estimate costs. May be nil.
+//
+// Returns:
+//   - *Tracker: A pointer to the newly created Tracker instance.
+func NewTracker(prices PriceTable) *Tracker {
+	return &Tracker{prices: prices}
+}
+
+// Add records the usage of a response generated for a feed.
+// Parameters:
+//   - feed: The URL of the summarized feed.
+//   - resp: The response whose backend and usage are recorded.
+func (t *Tracker) Add(feed string, resp *genAi.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.feeds {
+		if t.feeds[i].Feed == feed && t.feeds[i].Backend == resp.Backend {
+			t.feeds[i].Requests++
+			t.feeds[i].Usage = t.feeds[i].Usage.Add(resp.Usage)
+			return
+		}
+	}
+	t.feeds = append(t.feeds, FeedUsage{Feed: feed, Backend: resp.Backend, Requests: 1, Usage: resp.Usage})
+}
+
+// feedKey is the context key of the feed URL set by WithFeed.
+type feedKey struct{}
+
+// WithFeed returns a context whose requests Record attributes to a feed.
+// Parameters:
+//   - ctx: The parent context.
+//   - feed: The URL of the feed the requests are sent for.
+//
+// Returns:
+//   - context.Context: The context carrying the feed.
+func WithFeed(ctx context.Context, feed string) context.Context {
+	return context.WithValue(ctx, feedKey{}, feed)
+}
+
+// Record records the usage of a response for the feed set on ctx with WithFeed.
+// It has the signature of genAi.UsageRecorder, so that a genAi.UsageRecordingClient
+// records the usage of every request it sees.
+// Parameters:
+//   - ctx: The context of the request.
+//   - resp: The response whose backend and usage are recorded.
+func (t *Tracker) Record(ctx context.Context, resp *genAi.Response) {
+	feed, _ := ctx.Value(feedKey{}).(string)
+	t.Add(feed, resp)
+}
+
+// Report returns the usage recorded so far with cost estimates.
+//
+// Returns:
+//   - Report: The usage per feed and of the whole run.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := Report{Feeds: make([]FeedUsage, 0, len(t.feeds))}
+	unpriced := make(map[string]bool)
+	for _, f := range t.feeds {
+		if cost, ok := t.prices.Cost(f.Backend, f.Usage); ok {
+			f.Cost = &cost
+			report.Cost += cost
+		} else if !unpriced[f.Backend] {
+			unpriced[f.Backend] = true
+			report.Unpriced = append(report.Unpriced, f.Backend)
+		}
+		report.Total = report.Total.Add(f.Usage)
+		report.Feeds = append(report.Feeds, f)
+	}
+	return report
+}
+
+// WriteText writes the report as a table.
+// Parameters:
+//   - w: The writer to write to.
+//
+// Returns:
+//   - error: An error if writing fails.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "feed\tbackend\trequests\tprompt\tcached\toutput\tcost (USD)\t")
+	for _, f := range r.Feeds {
+		cost := "-"
+		if f.Cost != nil {
+			cost = fmt.Sprintf("%.6f", *f.Cost)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t\n",
+			f.Feed, f.Backend, f.Requests, f.Usage.PromptTokens, f.Usage.CachedTokens, f.Usage.OutputTokens, cost)
+	}
+	fmt.Fprintf(tw, "total\t\t\t%d\t%d\t%d\t%.6f\t\n", r.Total.PromptTokens, r.Total.CachedTokens, r.Total.OutputTokens, r.Cost)
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("failed to write usage report: %w", err)
+	}
+	if len(r.Unpriced) > 0 {
+		if _, err := fmt.Fprintf(w, "no price for %v; their cost is not included\n", r.Unpriced); err != nil {
+			return fmt.Errorf("failed to write usage report: %w", err)
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report to a JSON file.
+// Parameters:
+//   - path: The path of the file to write.
+//
+// Returns:
+//   - error: An error if the report cannot be marshalled or written.
+func (r Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write usage report to %s: %w", path, err)
+	}
+	return nil
+}
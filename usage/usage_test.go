@@ -0,0 +1,98 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	genAi "feed-summarizer/ai_client"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPrices = PriceTable{
+	"gemini:flash": {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+	"openai:mini":  {Input: 0.15, Output: 0.60},
+}
+
+func TestPriceTable_Cost(t *testing.T) {
+	cost, ok := testPrices.Cost("gemini:flash", genAi.Usage{PromptTokens: 1_000_000, CachedTokens: 400_000, OutputTokens: 500_000})
+	assert.True(t, ok)
+	assert.InDelta(t, 0.06+0.01+0.20, cost, 1e-9)
+
+	cost, ok = testPrices.Cost("openai:mini", genAi.Usage{PromptTokens: 2_000_000, CachedTokens: 1_000_000})
+	assert.True(t, ok)
+	assert.InDelta(t, 0.30, cost, 1e-9, "cached tokens without a cached price are charged as input")
+
+	_, ok = testPrices.Cost("ollama:llama3.2", genAi.Usage{PromptTokens: 100})
+	assert.False(t, ok)
+}
+
+func TestTracker_Report(t *testing.T) {
+	tracker := NewTracker(testPrices)
+	tracker.Add("https://a.example/rss", &genAi.Response{Backend: "gemini:flash", Usage: genAi.Usage{PromptTokens: 1_000_000, OutputTokens: 100_000}})
+	tracker.Add("https://a.example/rss", &genAi.Response{Backend: "gemini:flash", Usage: genAi.Usage{PromptTokens: 1_000_000, OutputTokens: 100_000}})
+	tracker.Add("https://b.example/rss", &genAi.Response{Backend: "ollama:llama3.2", Usage: genAi.Usage{PromptTokens: 500, OutputTokens: 50}})
+
+	report := tracker.Report()
+	assert.Len(t, report.Feeds, 2)
+	assert.Equal(t, 2, report.Feeds[0].Requests)
+	assert.Equal(t, genAi.Usage{PromptTokens: 2_000_000, OutputTokens: 200_000}, report.Feeds[0].Usage)
+	assert.InDelta(t, 0.28, *report.Feeds[0].Cost, 1e-9)
+	assert.Nil(t, report.Feeds[1].Cost)
+	assert.Equal(t, genAi.Usage{PromptTokens: 2_000_500, OutputTokens: 200_050}, report.Total)
+	assert.InDelta(t, 0.28, report.Cost, 1e-9)
+	assert.Equal(t, []string{"ollama:llama3.2"}, report.Unpriced)
+
+	var sb strings.Builder
+	assert.NoError(t, report.WriteText(&sb))
+	assert.Contains(t, sb.String(), "https://a.example/rss")
+	assert.Contains(t, sb.String(), "0.280000")
+	assert.Contains(t, sb.String(), "no price for [ollama:llama3.2]")
+
+	path := filepath.Join(t.TempDir(), "usage.json")
+	assert.NoError(t, report.WriteJSON(path))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var decoded Report
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, report.Total, decoded.Total)
+}
+
+// usageClient returns a response with a fixed usage.
+type usageClient struct{}
+
+func (usageClient) Send(_ context.Context, _ genAi.Request) (*genAi.Response, error) {
+	return &genAi.Response{Backend: "gemini:flash", Usage: genAi.Usage{PromptTokens: 100, OutputTokens: 10}}, nil
+}
+
+func TestTracker_Record(t *testing.T) {
+	tracker := NewTracker(testPrices)
+	client := genAi.NewUsageRecordingClient(usageClient{}, tracker.Record)
+	req := genAi.NewUserRequest("", "feed", genAi.GenerationOptions{})
+
+	for _, feed := range []string{"https://a.example/rss", "https://a.example/rss", "https://b.example/rss"} {
+		_, err := client.Send(WithFeed(context.Background(), feed), req)
+		assert.NoError(t, err)
+	}
+
+	report := tracker.Report()
+	assert.Len(t, report.Feeds, 2)
+	assert.Equal(t, "https://a.example/rss", report.Feeds[0].Feed)
+	assert.Equal(t, 2, report.Feeds[0].Requests)
+	assert.Equal(t, genAi.Usage{PromptTokens: 200, OutputTokens: 20}, report.Feeds[0].Usage)
+	assert.Equal(t, "https://b.example/rss", report.Feeds[1].Feed)
+	assert.Equal(t, genAi.Usage{PromptTokens: 300, OutputTokens: 30}, report.Total)
+}
+
+func TestLoadPriceTable(t *testing.T) {
+	prices, err := LoadPriceTable(filepath.Join("..", "templates", "price_table.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, prices, "gemini:gemini-2.5-flash-lite")
+
+	_, err = LoadPriceTable(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}